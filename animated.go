@@ -0,0 +1,80 @@
+package vectopng
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// findVector advances the decoder to the start of the <vector> element. It
+// is either the root element or, for an <animated-vector>, given inline as
+// its <aapt:attr name="android:drawable">, whose static frame is rendered
+// without the animations. Other root elements return ErrNotVector.
+func findVector(d *xml.Decoder) (xml.StartElement, error) {
+	root, err := nextStartElement(d)
+	if err != nil {
+		return xml.StartElement{}, err
+	}
+	switch root.Name.Local {
+	case "vector":
+		return root, nil
+	case "animated-vector":
+	default:
+		return xml.StartElement{}, ErrNotVector
+	}
+
+	depth := 0
+	inDrawable := false
+	for depth >= 0 {
+		token, err := d.Token()
+		if err != nil {
+			return xml.StartElement{}, err
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			depth++
+			if depth == 1 && t.Name.Space == aaptNamespace && t.Name.Local == "attr" && attrValue(t, "name") == "android:drawable" {
+				inDrawable = true
+			} else if depth == 2 && inDrawable && t.Name.Local == "vector" {
+				return t, nil
+			}
+		case xml.EndElement:
+			if depth == 1 {
+				inDrawable = false
+			}
+			depth--
+		}
+	}
+
+	if drawable := attrValue(root, "drawable"); drawable != "" {
+		return xml.StartElement{}, fmt.Errorf("animated-vector references the drawable \"%s\", only a <vector> given inline as <aapt:attr name=\"android:drawable\"> can be rendered", drawable)
+	}
+	return xml.StartElement{}, errors.New("animated-vector has no <vector> drawable")
+}
+
+// nextStartElement returns the next start element of the decoder.
+func nextStartElement(d *xml.Decoder) (xml.StartElement, error) {
+	for {
+		token, err := d.Token()
+		if err == io.EOF {
+			return xml.StartElement{}, ErrNotVector
+		} else if err != nil {
+			return xml.StartElement{}, err
+		}
+		if start, ok := token.(xml.StartElement); ok {
+			return start, nil
+		}
+	}
+}
+
+// attrValue returns the value of the attribute with the given local name of
+// the element or "" if it has none.
+func attrValue(start xml.StartElement, name string) string {
+	for _, attr := range start.Attr {
+		if attr.Name.Local == name {
+			return attr.Value
+		}
+	}
+	return ""
+}
@@ -0,0 +1,33 @@
+package vectopng
+
+import (
+	"github.com/tdewolff/canvas"
+)
+
+// Bounds returns the extent of the paths of the vector drawable in viewport
+// coordinates without rendering it. Strokes are included with their width,
+// paths that would not be drawn are left out. The rectangle is empty if no
+// path is drawn. Colors, tints and the Only and Exclude options are applied
+// like by Convert, size and scale options have no effect.
+func Bounds(xmlData []byte, opts Options) (canvas.Rect, error) {
+	vec, err := parseVector(xmlData, opts)
+	if err != nil {
+		return canvas.Rect{}, err
+	}
+
+	// A renderer without context only measures the paths.
+	r := &renderer{opts: &opts, empty: true, vec: vec, groups: canvas.Identity, transform: canvas.Identity, alpha: vec.Alpha}
+	r.tint, err = parseTint(vec, &opts, &r.stats)
+	if err != nil {
+		return canvas.Rect{}, err
+	}
+	if err := r.drawNodes(vec.Children); err != nil {
+		return canvas.Rect{}, err
+	}
+	r.warnUnknownNames(opts.Only)
+	r.warnUnknownNames(opts.Exclude)
+	if opts.Stats != nil {
+		*opts.Stats = r.stats
+	}
+	return r.bounds, nil
+}
@@ -0,0 +1,129 @@
+package vectopng
+
+import (
+	"math"
+	"slices"
+
+	"github.com/tdewolff/canvas"
+)
+
+// intersect returns the area of path a within path b. If one of them is a
+// convex shape, such as the adaptive icon masks and most clip-paths, the
+// other one is flattened and clipped to it with the Sutherland-Hodgman
+// algorithm, since canvas.Path.And loses corners or fails when the paths
+// share edges, for example a background inside a clip-path covering the
+// whole viewport. Other shapes fall back to canvas.Path.And.
+func intersect(a *canvas.Path, b *canvas.Path) *canvas.Path {
+	if polygon, ok := convexPolygon(b); ok {
+		return clipToPolygon(a, polygon)
+	}
+	if polygon, ok := convexPolygon(a); ok {
+		return clipToPolygon(b, polygon)
+	}
+	return a.And(b)
+}
+
+// flattenTolerance returns the tolerance for flattening the path, which is
+// small enough for any scale of the image.
+func flattenTolerance(path *canvas.Path) float64 {
+	bounds := path.Bounds()
+	if size := math.Max(bounds.W, bounds.H); size > 0 {
+		return size * 1e-4
+	}
+	return canvas.Tolerance
+}
+
+// polygon returns the corners of a flattened subpath without repeating the
+// start point at the end.
+func polygon(subpath *canvas.Path) []canvas.Point {
+	points := subpath.Coords()
+	if n := len(points); n > 1 && points[0].Equals(points[n-1]) {
+		points = points[:n-1]
+	}
+	return points
+}
+
+// convexPolygon returns the corners of the path in counter clockwise order
+// if it is a single convex subpath. Turning the same way at every corner is
+// not enough, since a pentagram does so too, but it turns twice in total.
+func convexPolygon(path *canvas.Path) ([]canvas.Point, bool) {
+	subpaths := path.Flatten(flattenTolerance(path)).Split()
+	if len(subpaths) != 1 {
+		return nil, false
+	}
+	points := polygon(subpaths[0])
+	if len(points) < 3 {
+		return nil, false
+	}
+	sign, turning := 0.0, 0.0
+	for i := range points {
+		a, b, c := points[i], points[(i+1)%len(points)], points[(i+2)%len(points)]
+		cross := b.Sub(a).PerpDot(c.Sub(b))
+		turning += math.Atan2(cross, b.Sub(a).Dot(c.Sub(b)))
+		if math.Abs(cross) < canvas.Epsilon {
+			continue
+		} else if sign == 0 {
+			sign = math.Copysign(1, cross)
+		} else if math.Copysign(1, cross) != sign {
+			return nil, false
+		}
+	}
+	if math.Abs(math.Abs(turning)-2*math.Pi) > 1e-6 {
+		return nil, false
+	}
+	if sign < 0 {
+		slices.Reverse(points)
+	}
+	return points, sign != 0
+}
+
+// clipToPolygon clips each subpath of the flattened path to the convex
+// polygon given counter clockwise. The windings of the clipped subpaths
+// within the polygon are the same as before, so both fill rules still
+// apply.
+func clipToPolygon(path *canvas.Path, clip []canvas.Point) *canvas.Path {
+	result := &canvas.Path{}
+	for _, subpath := range path.Flatten(flattenTolerance(path)).Split() {
+		points := polygon(subpath)
+		for i := range clip {
+			a, b := clip[i], clip[(i+1)%len(clip)]
+			points = clipToEdge(points, a, b)
+		}
+		if len(points) < 3 {
+			continue
+		}
+		result.MoveTo(points[0].X, points[0].Y)
+		for _, p := range points[1:] {
+			result.LineTo(p.X, p.Y)
+		}
+		result.Close()
+	}
+	return result
+}
+
+// clipToEdge keeps the part of the polygon on the left of the edge from a
+// to b.
+func clipToEdge(points []canvas.Point, a canvas.Point, b canvas.Point) []canvas.Point {
+	edge := b.Sub(a)
+	inside := func(p canvas.Point) bool {
+		return edge.PerpDot(p.Sub(a)) >= 0
+	}
+	crossing := func(p, q canvas.Point) canvas.Point {
+		t := edge.PerpDot(p.Sub(a)) / edge.PerpDot(p.Sub(q))
+		return p.Interpolate(q, t)
+	}
+
+	var clipped []canvas.Point
+	for i, q := range points {
+		p := points[(i+len(points)-1)%len(points)]
+		if inside(q) {
+			if !inside(p) {
+				clipped = append(clipped, crossing(p, q))
+			}
+			clipped = append(clipped, q)
+		} else if inside(p) {
+			clipped = append(clipped, crossing(p, q))
+		}
+	}
+	return clipped
+}
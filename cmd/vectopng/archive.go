@@ -0,0 +1,88 @@
+package main
+
+import (
+	"archive/zip"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// splitArchivePath splits p into the archive and the entry if p names an
+// entry of a zip archive such as lib.aar!res/drawable/ic_foo.xml. The entry
+// is empty for the archive itself, given as lib.aar or lib.aar!.
+func splitArchivePath(p string) (archive string, entry string, ok bool) {
+	archive, entry, _ = strings.Cut(p, "!")
+	switch strings.ToLower(filepath.Ext(archive)) {
+	case ".aar", ".jar", ".zip":
+		return archive, strings.TrimPrefix(entry, "/"), true
+	}
+	return "", "", false
+}
+
+// readInput reads a vector file, an entry of a zip archive or, for "-",
+// stdin.
+func readInput(p string) ([]byte, error) {
+	if p == "-" {
+		return io.ReadAll(os.Stdin)
+	}
+	archive, entry, ok := splitArchivePath(p)
+	if !ok {
+		return os.ReadFile(p)
+	} else if entry == "" {
+		return nil, fmt.Errorf("no entry of the archive given, such as %s!res/drawable/ic_foo.xml", archive)
+	}
+
+	r, err := zip.OpenReader(archive)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	f, err := r.Open(entry)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return io.ReadAll(f)
+}
+
+// archiveEntries returns the files of the archive matching the pattern as
+// archive!entry paths in sorted order. An empty pattern matches all XML
+// files.
+func archiveEntries(archive string, pattern string) ([]string, error) {
+	r, err := zip.OpenReader(archive)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	var entries []string
+	for _, f := range r.File {
+		if f.FileInfo().IsDir() {
+			continue
+		}
+		if pattern == "" {
+			if strings.EqualFold(path.Ext(f.Name), ".xml") {
+				entries = append(entries, archive+"!"+f.Name)
+			}
+		} else if ok, err := path.Match(pattern, f.Name); err != nil {
+			return nil, err
+		} else if ok {
+			entries = append(entries, archive+"!"+f.Name)
+		}
+	}
+	sort.Strings(entries)
+	return entries, nil
+}
+
+// archiveOutput returns the image file for an archive entry. The entry path
+// is kept below outDir or, if outDir is empty, next to the archive.
+func archiveOutput(archive string, entry string, outDir string, ext string) string {
+	if outDir == "" {
+		outDir = filepath.Dir(archive)
+	}
+	return filepath.Join(outDir, filepath.FromSlash(pathWithoutExtension(entry))+ext)
+}
@@ -0,0 +1,312 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"perron2.ch/vectopng"
+)
+
+// batchOptions controls the conversion of vector files.
+type batchOptions struct {
+	// Jobs is the number of concurrent conversions, or one per CPU if < 1.
+	Jobs int
+	// Verbose prints the statistics of each conversion.
+	Verbose bool
+	// DryRun renders the vector files without writing any images.
+	DryRun bool
+	// Progress shows the number of finished conversions while converting.
+	Progress bool
+	// FailFast stops the batch at the first failed conversion. Files that
+	// have not been started by then are not converted.
+	FailFast bool
+	// Timeout limits the time of each conversion if greater than zero.
+	Timeout time.Duration
+	// CacheFile is the file recording the converted vector files. If not
+	// empty, vector files that did not change since they were converted with
+	// the same options are not converted again.
+	CacheFile string
+	// StdoutFormat encodes the image written to stdout for the output file
+	// "-" as raw image (png), base64 or datauri.
+	StdoutFormat string
+}
+
+type batchSummary struct {
+	Converted int
+	Unchanged int
+	Skipped   int
+	Failed    int
+	// Aborted is the number of files left out by batchOptions.FailFast.
+	Aborted  int
+	Manifest []manifestEntry
+}
+
+// convertDir converts all vector drawables found in dir. The images are
+// written next to the vector files or, if outDir is set, into the same
+// relative location below outDir. XML files that are not vector drawables
+// are skipped. Errors are reported in file order once all conversions are
+// done, followed by the statistics if batch.Verbose is set. In a dry run,
+// no images are written and the result of each file is printed instead.
+func convertDir(dir string, outDir string, batch batchOptions, opts vectopng.Options) (batchSummary, error) {
+	var files []string
+	err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() && strings.EqualFold(filepath.Ext(p), ".xml") {
+			files = append(files, p)
+		}
+		return nil
+	})
+	if err != nil {
+		return batchSummary{}, err
+	}
+
+	pngFiles := make([]string, len(files))
+	for i, p := range files {
+		pngFiles[i] = pathWithoutExtension(p) + outputExtension(opts)
+		if outDir != "" {
+			rel, err := filepath.Rel(dir, pngFiles[i])
+			if err != nil {
+				return batchSummary{}, err
+			}
+			pngFiles[i] = filepath.Join(outDir, rel)
+		}
+	}
+	return convertFiles(files, pngFiles, batch, opts), nil
+}
+
+// convertFiles converts the vector files to the image files of the same
+// index like convertDir.
+func convertFiles(files []string, pngFiles []string, batch batchOptions, opts vectopng.Options) batchSummary {
+	jobs := batch.Jobs
+	if jobs < 1 {
+		jobs = runtime.NumCPU()
+	}
+	var prog *progress
+	if batch.Progress {
+		prog = newProgress(len(files))
+	}
+	var cache *conversionCache
+	if batch.CacheFile != "" && !batch.DryRun {
+		cache = loadCache(batch.CacheFile, opts)
+	}
+	conversions := make([]conversion, len(files))
+	started := make([]bool, len(files))
+	indexes := make(chan int)
+	var failed atomic.Bool
+	var wg sync.WaitGroup
+	for j := 0; j < jobs; j++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				conversions[i] = convertCached(cache, files[i], pngFiles[i], batch, opts)
+				if err := conversions[i].Err; err != nil && !errors.Is(err, vectopng.ErrNotVector) {
+					failed.Store(true)
+				}
+				if prog != nil {
+					prog.add()
+				}
+			}
+		}()
+	}
+	for i := range files {
+		if batch.FailFast && failed.Load() {
+			break
+		}
+		started[i] = true
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+	if prog != nil {
+		prog.finish()
+	}
+	if cache != nil {
+		if err := cache.save(); err != nil {
+			printFileError(batch.CacheFile, "Cannot write cache file", err)
+		}
+	}
+
+	var summary batchSummary
+	var total conversion
+	for i, conv := range conversions {
+		if !started[i] {
+			summary.Aborted++
+			continue
+		}
+		for _, warning := range conv.Warnings {
+			printWarning(fmt.Sprintf("%s: %s", conv.Source, warning))
+		}
+		if errors.Is(conv.Err, vectopng.ErrNotVector) {
+			summary.Skipped++
+		} else if conv.Err != nil {
+			if batch.DryRun {
+				printResult(conv)
+			} else {
+				printFileError(conv.Source, "Cannot convert", conv.Err)
+			}
+			summary.Failed++
+		} else {
+			if batch.DryRun {
+				printResult(conv)
+			}
+			if conv.Unchanged {
+				summary.Unchanged++
+			} else {
+				summary.Converted++
+			}
+			summary.Manifest = append(summary.Manifest, newManifestEntries(conv)...)
+			if batch.Verbose && !conv.Unchanged {
+				printStats(conv.Source, conv)
+			}
+			total.Stats.Add(conv.Stats)
+			total.Elapsed += conv.Elapsed
+		}
+	}
+	if batch.Verbose {
+		printStats("total", total)
+	}
+	return summary
+}
+
+// conversion is the result of converting a single vector file.
+type conversion struct {
+	Source   string
+	Outputs  []vectopng.Output
+	Warnings []string
+	Stats    vectopng.Stats
+	Elapsed  time.Duration
+	Err      error
+	// Unchanged is set if the conversion was skipped because the vector
+	// file did not change, see conversionCache.
+	Unchanged bool
+}
+
+// convertCached converts a single vector file like convertTimeout unless the
+// cache finds it unchanged. Only conversions that finished in time are
+// stored in the cache.
+func convertCached(cache *conversionCache, vectorFile string, pngFile string, batch batchOptions, opts vectopng.Options) conversion {
+	if cache == nil {
+		return convertTimeout(vectorFile, pngFile, batch, opts)
+	}
+	hash, err := cache.hash(vectorFile, pngFile)
+	if err != nil {
+		return conversion{Source: vectorFile, Err: err}
+	}
+	if conv, ok := cache.lookup(vectorFile, hash); ok {
+		return conv
+	}
+	conv := convertTimeout(vectorFile, pngFile, batch, opts)
+	if conv.Err == nil {
+		cache.store(hash, conv)
+	}
+	return conv
+}
+
+// convertTimeout converts a single vector file like convertFile, but fails
+// if the conversion takes longer than batch.Timeout. Rendering cannot be
+// interrupted, so a conversion that timed out keeps running in the
+// background until it finishes, and may still write its images, while the
+// batch goes on with the next files.
+func convertTimeout(vectorFile string, pngFile string, batch batchOptions, opts vectopng.Options) conversion {
+	if batch.Timeout <= 0 {
+		return convertFile(vectorFile, pngFile, batch, opts)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), batch.Timeout)
+	defer cancel()
+	result := make(chan conversion, 1)
+	go func() {
+		result <- convertFile(vectorFile, pngFile, batch, opts)
+	}()
+	select {
+	case conv := <-result:
+		return conv
+	case <-ctx.Done():
+		return conversion{Source: vectorFile, Elapsed: batch.Timeout, Err: fmt.Errorf("timed out after %v", batch.Timeout)}
+	}
+}
+
+// convertFile converts a single vector file. In a dry run, the vector file
+// is only rendered to catch errors, but no images are written. The output
+// file "-" writes the image to stdout.
+func convertFile(vectorFile string, pngFile string, batch batchOptions, opts vectopng.Options) conversion {
+	return convertLayers([]string{vectorFile}, pngFile, batch, opts)
+}
+
+// convertLayers converts the vector files as layers of one image like
+// convertFile.
+func convertLayers(vectorFiles []string, pngFile string, batch batchOptions, opts vectopng.Options) conversion {
+	conv := conversion{Source: strings.Join(vectorFiles, ",")}
+	start := time.Now()
+	opts.Warn = func(warning string) {
+		conv.Warnings = append(conv.Warnings, warning)
+	}
+	opts.Stats = &conv.Stats
+
+	layers := make([][]byte, len(vectorFiles))
+	for i, vectorFile := range vectorFiles {
+		xmlData, err := readInput(vectorFile)
+		if err != nil {
+			conv.Err = err
+			return conv
+		}
+		layers[i] = xmlData
+	}
+
+	c, err := vectopng.ConvertLayers(layers, opts)
+	if err != nil {
+		conv.Err = err
+		return conv
+	}
+	if batch.DryRun {
+		conv.Elapsed = time.Since(start)
+		return conv
+	}
+	if pngFile == "-" {
+		var output vectopng.Output
+		output, conv.Err = writeStdout(c, batch.StdoutFormat, opts)
+		conv.Outputs = []vectopng.Output{output}
+	} else {
+		conv.Outputs, conv.Err = vectopng.Save(c, pngFile, opts)
+	}
+	conv.Elapsed = time.Since(start)
+	return conv
+}
+
+// printResult prints the result of a dry run conversion.
+func printResult(conv conversion) {
+	if conv.Err != nil {
+		fmt.Printf("FAIL %s (%v)\n", conv.Source, conv.Err)
+	} else {
+		printInfo("OK %s\n", conv.Source)
+	}
+}
+
+// printStats prints the statistics of a conversion to stderr.
+func printStats(label string, conv conversion) {
+	stats := conv.Stats
+	msg := fmt.Sprintf("%s: %d paths drawn, %d skipped, %d colors resolved, %d unresolved",
+		label, stats.PathsDrawn, stats.PathsSkipped, stats.ColorsResolved, stats.ColorsUnresolved)
+	if stats.ColorsClamped > 0 {
+		msg += fmt.Sprintf(", %d clamped", stats.ColorsClamped)
+	}
+	if stats.Width > 0 {
+		msg += fmt.Sprintf(", canvas %gx%g mm", stats.Width, stats.Height)
+	}
+	for _, output := range conv.Outputs {
+		msg += fmt.Sprintf(", %dx%d px", output.Width, output.Height)
+	}
+	msg += fmt.Sprintf(", %v", conv.Elapsed.Round(time.Microsecond))
+	fmt.Fprintln(os.Stderr, msg)
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+
+	"perron2.ch/vectopng"
+)
+
+// cacheFileName is the name of the file next to the images of a batch
+// conversion that records which vector files they were converted from.
+const cacheFileName = ".vectopng-cache.json"
+
+// conversionCache remembers a hash of each converted vector file and the
+// options, so that unchanged files are not converted again. It is safe for
+// concurrent use.
+type conversionCache struct {
+	file     string
+	settings string
+	mu       sync.Mutex
+	entries  map[string]cacheEntry
+}
+
+// cacheEntry is the cached result of converting a vector file.
+type cacheEntry struct {
+	Hash     string            `json:"hash"`
+	Outputs  []vectopng.Output `json:"outputs"`
+	Warnings []string          `json:"warnings,omitempty"`
+}
+
+// loadCache reads the cache file. A missing or invalid file results in an
+// empty cache. The settings are all options that change the images.
+func loadCache(file string, opts vectopng.Options) *conversionCache {
+	opts.Warn = nil
+	opts.Stats = nil
+	opts.PathCache = nil
+	cache := &conversionCache{
+		file:     file,
+		settings: fmt.Sprintf("%#v", opts),
+		entries:  make(map[string]cacheEntry),
+	}
+	if data, err := os.ReadFile(file); err == nil {
+		json.Unmarshal(data, &cache.entries)
+	}
+	return cache
+}
+
+// hash returns the hash of the vector file, the image file it is converted
+// to and the settings.
+func (cc *conversionCache) hash(vectorFile string, pngFile string) (string, error) {
+	data, err := readInput(vectorFile)
+	if err != nil {
+		return "", err
+	}
+	h := sha256.New()
+	fmt.Fprintf(h, "%s\x00%s\x00", cc.settings, pngFile)
+	h.Write(data)
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// lookup returns the cached conversion of the vector file if the hash is
+// unchanged and all images still exist.
+func (cc *conversionCache) lookup(vectorFile string, hash string) (conversion, bool) {
+	cc.mu.Lock()
+	entry, ok := cc.entries[vectorFile]
+	cc.mu.Unlock()
+	if !ok || entry.Hash != hash {
+		return conversion{}, false
+	}
+	for _, output := range entry.Outputs {
+		if _, err := os.Stat(output.File); err != nil {
+			return conversion{}, false
+		}
+	}
+	return conversion{Source: vectorFile, Outputs: entry.Outputs, Warnings: entry.Warnings, Unchanged: true}, true
+}
+
+// store records a successful conversion.
+func (cc *conversionCache) store(hash string, conv conversion) {
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+	cc.entries[conv.Source] = cacheEntry{Hash: hash, Outputs: conv.Outputs, Warnings: conv.Warnings}
+}
+
+// save writes the cache file.
+func (cc *conversionCache) save() error {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetIndent("", "  ")
+	cc.mu.Lock()
+	err := encoder.Encode(cc.entries)
+	cc.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	return writeFile(cc.file, buf.Bytes())
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+)
+
+// applyConfig sets the flags to the values of a JSON config file such as
+// {"scale": 2, "ios": true, "colors": ["colors.xml"]}, except for the flags
+// given on the command line, which take precedence. Arrays set repeatable
+// flags once per value.
+func applyConfig(flags *flag.FlagSet, configFile string) error {
+	data, err := os.ReadFile(configFile)
+	if err != nil {
+		return err
+	}
+	var config map[string]any
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(&config); err != nil {
+		return err
+	}
+
+	explicit := make(map[string]bool)
+	flags.Visit(func(f *flag.Flag) {
+		explicit[f.Name] = true
+	})
+	names := make([]string, 0, len(config))
+	for name := range config {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		value := config[name]
+		f := flags.Lookup(name)
+		if f == nil || name == "config" {
+			return fmt.Errorf("unknown option \"%s\"", name)
+		}
+		if explicit[name] {
+			continue
+		}
+		values, ok := value.([]any)
+		if !ok {
+			values = []any{value}
+		}
+		for _, v := range values {
+			s, err := configString(v)
+			if err == nil {
+				err = f.Value.Set(s)
+			}
+			if err != nil {
+				return fmt.Errorf("invalid value for option \"%s\": %w", name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// configString converts a JSON value to the string that would be given on
+// the command line.
+func configString(v any) (string, error) {
+	switch v := v.(type) {
+	case string:
+		return v, nil
+	case json.Number:
+		return v.String(), nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	}
+	return "", fmt.Errorf("unsupported value %v", v)
+}
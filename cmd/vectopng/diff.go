@@ -0,0 +1,98 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"image"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"perron2.ch/vectopng"
+)
+
+// diff renders two vector drawables, or one and a reference PNG image, and
+// reports how they differ. The program exits with 1 if the percentage of
+// differing pixels exceeds the threshold.
+func diff(args []string) {
+	flags := flag.NewFlagSet("diff", flag.ExitOnError)
+	opts := vectopng.Options{Scale: 1.0, Opacity: 1.0}
+	colorDefs := make(vectopng.ColorDefs)
+	var colorsFiles stringList
+	var stringsFiles stringList
+	threshold := 0.0
+	outFile := ""
+	flags.Var(&colorDefs, "color", "Defines an (A)RGB value for a color name (name=#(a)rgb|(aa)rrggbb)")
+	flags.Var(&colorsFiles, "colors", "Defines an Android color resource file to be parsed for color definitions (can be repeated, later files override earlier ones)")
+	flags.Var(&stringsFiles, "strings", "Defines an Android string resource file for string references in pathData and colors (can be repeated, later files override earlier ones)")
+	flags.Var(&scaleValue{&opts.Scale, &opts.ScaleY}, "scale", "Scales the images by the given `factor` or by separate horizontal and vertical factors such as 2x1.5")
+	flags.Float64Var(&threshold, "threshold", threshold, "Defines the percentage of differing pixels that is still accepted")
+	flags.StringVar(&outFile, "out", outFile, "Writes a PNG image highlighting the differing pixels in red")
+	flags.Usage = func() {
+		fmt.Printf("Usage: %s diff [options] <vector-image-input> <vector-image-input|png-image-input>\n\n", filepath.Base(os.Args[0]))
+		flags.PrintDefaults()
+		fmt.Println()
+	}
+	flags.Parse(args)
+	if flags.NArg() != 2 {
+		printError("Two images to compare are required", nil)
+		flags.Usage()
+		os.Exit(exitUsage)
+	}
+	opts.Colors = buildColorDefs(colorDefs, colorsFiles)
+	opts.Strings = buildStringDefs(stringsFiles)
+	opts.Warn = printWarning
+
+	a := diffImage(flags.Arg(0), opts)
+	b := diffImage(flags.Arg(1), opts)
+	d, err := vectopng.Compare(a, b)
+	if err != nil {
+		errorExit("Cannot compare images", err)
+	}
+	if outFile != "" {
+		var buf bytes.Buffer
+		if err := png.Encode(&buf, vectopng.HighlightDifference(a, b)); err != nil {
+			errorExit("Cannot encode difference image", err)
+		}
+		if err := writeFile(outFile, buf.Bytes()); err != nil {
+			errorExit("Cannot write difference image", err)
+		}
+	}
+
+	if d.Pixels == 0 {
+		fmt.Println("No differences")
+	} else {
+		fmt.Printf("%.2f%% of pixels differ (%d of %d) within %d,%d-%d,%d\n", d.Percent(), d.Pixels, d.Total,
+			d.Bounds.Min.X, d.Bounds.Min.Y, d.Bounds.Max.X, d.Bounds.Max.Y)
+	}
+	if d.Percent() > threshold {
+		os.Exit(exitFailure)
+	}
+}
+
+// diffImage reads a PNG image or renders a vector drawable to the image
+// Save would write.
+func diffImage(p string, opts vectopng.Options) image.Image {
+	data, err := readInput(p)
+	if err != nil {
+		fileErrorExit(p, "Cannot read", err)
+	}
+	if strings.EqualFold(filepath.Ext(p), ".png") {
+		img, err := png.Decode(bytes.NewReader(data))
+		if err != nil {
+			fileErrorExit(p, "Cannot decode", err)
+		}
+		return img
+	}
+
+	c, err := vectopng.Convert(data, opts)
+	if errors.Is(err, vectopng.ErrNotVector) {
+		fileErrorExit(p, "Not a valid Android vector drawable", nil)
+	} else if err != nil {
+		fileErrorExit(p, "Cannot convert", err)
+	}
+	return vectopng.Rasterize(c, opts)
+}
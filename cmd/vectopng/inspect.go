@@ -0,0 +1,37 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"perron2.ch/vectopng"
+)
+
+// inspect prints the parsed vector drawable as JSON without rendering it.
+func inspect(args []string) {
+	flags := flag.NewFlagSet("inspect", flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Printf("Usage: %s inspect <vector-image-input>\n\n", filepath.Base(os.Args[0]))
+	}
+	flags.Parse(args)
+	if flags.NArg() != 1 {
+		printError("Input vector image parameter is missing", nil)
+		flags.Usage()
+		os.Exit(exitUsage)
+	}
+
+	xmlData, err := os.ReadFile(flags.Arg(0))
+	if err != nil {
+		fileErrorExit(flags.Arg(0), "Cannot read vector file", err)
+	}
+	data, err := vectopng.Inspect(xmlData)
+	if errors.Is(err, vectopng.ErrNotVector) {
+		fileErrorExit(flags.Arg(0), "Not a valid Android vector drawable", nil)
+	} else if err != nil {
+		fileErrorExit(flags.Arg(0), "Cannot parse vector file", err)
+	}
+	fmt.Println(string(data))
+}
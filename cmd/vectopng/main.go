@@ -0,0 +1,606 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"image/color"
+	"image/jpeg"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/tdewolff/canvas"
+	"perron2.ch/vectopng"
+)
+
+const version = "1.0"
+
+// stringList is a flag that can be given several times.
+type stringList []string
+
+func (sl *stringList) String() string {
+	return strings.Join(*sl, ",")
+}
+
+func (sl *stringList) Set(value string) error {
+	*sl = append(*sl, value)
+	return nil
+}
+
+// scaleValue is a flag for a uniform scale factor or for separate x and y
+// factors given as XxY.
+type scaleValue struct {
+	x *float64
+	y *float64
+}
+
+func (sv *scaleValue) String() string {
+	if sv.x == nil {
+		return ""
+	} else if *sv.y > 0 {
+		return fmt.Sprintf("%gx%g", *sv.x, *sv.y)
+	}
+	return strconv.FormatFloat(*sv.x, 'g', -1, 64)
+}
+
+func (sv *scaleValue) Set(value string) error {
+	xs, ys, found := strings.Cut(value, "x")
+	x, err := strconv.ParseFloat(xs, 64)
+	if err != nil || x <= 0 {
+		return fmt.Errorf("invalid scale \"%s\"", value)
+	}
+	y := 0.0
+	if found {
+		y, err = strconv.ParseFloat(ys, 64)
+		if err != nil || y <= 0 {
+			return fmt.Errorf("invalid scale \"%s\"", value)
+		}
+	}
+	*sv.x = x
+	*sv.y = y
+	return nil
+}
+
+// paddingValue is a flag for a padding in pixels or, with a % suffix, in
+// percent.
+type paddingValue struct {
+	padding *float64
+	percent *bool
+}
+
+func (pv *paddingValue) String() string {
+	if pv.padding == nil {
+		return ""
+	} else if *pv.percent {
+		return strconv.FormatFloat(*pv.padding, 'g', -1, 64) + "%"
+	}
+	return strconv.FormatFloat(*pv.padding, 'g', -1, 64)
+}
+
+func (pv *paddingValue) Set(value string) error {
+	number, percent := strings.CutSuffix(value, "%")
+	padding, err := strconv.ParseFloat(number, 64)
+	if err != nil || padding < 0 {
+		return fmt.Errorf("invalid padding \"%s\"", value)
+	}
+	*pv.padding = padding
+	*pv.percent = percent
+	return nil
+}
+
+// viewportValue is a flag.Value for a viewport region given as x,y,w,h.
+type viewportValue struct {
+	rect *canvas.Rect
+}
+
+func (vv *viewportValue) String() string {
+	if vv.rect == nil || *vv.rect == (canvas.Rect{}) {
+		return ""
+	}
+	return fmt.Sprintf("%g,%g,%g,%g", vv.rect.X, vv.rect.Y, vv.rect.W, vv.rect.H)
+}
+
+func (vv *viewportValue) Set(value string) error {
+	parts := strings.Split(value, ",")
+	if len(parts) != 4 {
+		return fmt.Errorf("invalid viewport \"%s\", expected x,y,w,h", value)
+	}
+	var numbers [4]float64
+	for i, part := range parts {
+		number, err := strconv.ParseFloat(strings.TrimSpace(part), 64)
+		if err != nil {
+			return fmt.Errorf("invalid viewport \"%s\", expected x,y,w,h", value)
+		}
+		numbers[i] = number
+	}
+	if numbers[2] <= 0 || numbers[3] <= 0 {
+		return fmt.Errorf("invalid viewport \"%s\", width and height must be greater than zero", value)
+	}
+	*vv.rect = canvas.Rect{X: numbers[0], Y: numbers[1], W: numbers[2], H: numbers[3]}
+	return nil
+}
+
+func main() {
+	if len(os.Args) > 1 && os.Args[1] == "inspect" {
+		inspect(os.Args[2:])
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "diff" {
+		diff(os.Args[2:])
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "render" {
+		render(os.Args[2:])
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "sprite" {
+		sprite(os.Args[2:])
+		return
+	}
+
+	opts := vectopng.Options{Scale: 1.0, Opacity: 1.0, Quality: jpeg.DefaultQuality}
+	colorDefs := make(vectopng.ColorDefs)
+	var colorsFiles stringList
+	resDir := ""
+	theme := ""
+	var stringsFiles stringList
+	defaultColor := ""
+	outDir := ""
+	format := ""
+	adaptive := ""
+	colorProfile := "srgb"
+	gradientInterpolation := "srgb"
+	manifestFile := ""
+	summaryJSON := false
+	icoSizes := ""
+	verbose := false
+	showProgress := false
+	skipUnchanged := false
+	failFast := false
+	cachePaths := false
+	var timeout time.Duration
+	stdoutFormat := "png"
+	scales := ""
+	iosSuffixes := ""
+	iosBase := "1x"
+	dryRun := false
+	watchInput := false
+	background := ""
+	flatten := ""
+	tint := ""
+	jobs := 0
+	showVersion := false
+	configFile := ""
+	layers := ""
+	only := ""
+	exclude := ""
+	var vectorFiles []string
+	pngFile := ""
+
+	flag.Var(&colorDefs, "color", "Defines an (A)RGB value for a color name (name=#(a)rgb|(aa)rrggbb)")
+	flag.Var(&colorsFiles, "colors", "Defines an Android color resource file to be parsed for color definitions (can be repeated, later files override earlier ones)")
+	flag.StringVar(&resDir, "res-dir", resDir, "Reads the colors.xml and strings.xml files of the values folder of an Android res directory, before the -colors and -strings files")
+	flag.StringVar(&theme, "theme", theme, "Reads the values-<theme> folder of the -res-dir as well, such as night for the dark theme")
+	flag.Var(&stringsFiles, "strings", "Defines an Android string resource file for string references in pathData and colors (can be repeated, later files override earlier ones)")
+	flag.StringVar(&opts.DrawableDir, "drawable-dir", opts.DrawableDir, "Defines the directory of the vector drawables that paths reference with android:drawable=\"@drawable/name\"")
+	flag.StringVar(&defaultColor, "default-color", defaultColor, "Defines the color used for color references that cannot be resolved")
+	flag.Var(&scaleValue{&opts.Scale, &opts.ScaleY}, "scale", "Scales the image by the given `factor` or by separate horizontal and vertical factors such as 2x1.5")
+	flag.IntVar(&opts.PixelWidth, "pixel-width", opts.PixelWidth, "Defines the exact pixel width of the image (overrides -scale)")
+	flag.IntVar(&opts.PixelHeight, "pixel-height", opts.PixelHeight, "Defines the exact pixel height of the image (overrides -scale)")
+	flag.IntVar(&opts.MaxPixels, "max-pixels", vectopng.DefaultMaxPixels, "Defines the largest number of pixels of an image, larger images fail (negative for no limit)")
+	flag.Float64Var(&opts.Width, "width", opts.Width, "Overrides the canvas width attribute of the vector drawable")
+	flag.Float64Var(&opts.Height, "height", opts.Height, "Overrides the canvas height attribute of the vector drawable")
+	flag.Float64Var(&opts.Density, "px-density", opts.Density, "Defines the density in dpi that px dimensions of the vector drawable refer to (default 160)")
+	flag.Var(&viewportValue{&opts.Viewport}, "viewport", "Renders only the `region` x,y,w,h of the viewport, stretched to the canvas size (see -width, -height and -preserve-aspect)")
+	flag.BoolVar(&opts.PreserveAspect, "preserve-aspect", opts.PreserveAspect, "Scales the image uniformly and centers it if the canvas and viewport aspect ratios differ")
+	flag.BoolVar(&opts.UniformStroke, "uniform-stroke", opts.UniformStroke, "Draws strokes with the same width in both directions if the viewport is stretched non-uniformly")
+	flag.BoolVar(&opts.Trim, "trim", opts.Trim, "Crops the image to the bounds of the drawn paths")
+	flag.Var(&paddingValue{&opts.Padding, &opts.PaddingPercent}, "padding", "Adds a margin around the image given in `pixels` or in percent of the larger side such as 10%")
+	flag.Float64Var(&opts.OffsetX, "x", opts.OffsetX, "Translates the image in x direction")
+	flag.Float64Var(&opts.OffsetY, "y", opts.OffsetY, "Translates the image in y direction")
+	flag.BoolVar(&opts.NoAntialias, "no-antialias", opts.NoAntialias, "Draws the paths without anti-aliasing")
+	flag.BoolVar(&opts.PixelSnap, "pixel-snap", opts.PixelSnap, "Rounds the path coordinates to the pixel grid")
+	flag.BoolVar(&opts.SnapScale, "snap-scale", opts.SnapScale, "Stretches the drawing slightly to fill the rounded pixel size of the image")
+	flag.BoolVar(&opts.LinearBlend, "linear-blend", opts.LinearBlend, "Blends semi-transparent colors in linear RGB instead of sRGB")
+	flag.StringVar(&gradientInterpolation, "gradient-interpolation", gradientInterpolation, "Defines the color space of the colors between gradient stops (srgb|linear|oklab)")
+	flag.StringVar(&format, "format", format, "Defines the image format (png|jpeg|webp|svg|ico|icns, default from the output file extension)")
+	flag.StringVar(&icoSizes, "ico-sizes", icoSizes, "Defines the comma separated pixel sizes of the images in ICO files (default 16,32,48,64,128,256)")
+	flag.StringVar(&colorProfile, "color-profile", colorProfile, "Declares the color space of PNG images (srgb|none|<icc-profile-file>)")
+	flag.Float64Var(&opts.DPI, "dpi", opts.DPI, "Defines the density stored in PNG images (does not change the pixel size)")
+	flag.IntVar(&opts.Quality, "quality", opts.Quality, "Defines the quality (1-100) of JPEG images and the compression effort of lossless WebP images")
+	flag.StringVar(&tint, "tint", tint, "Recolors all paths with an (A)RGB value or color name, keeping their alpha, or replaces the android:tint of the vector")
+	flag.Float64Var(&opts.Opacity, "opacity", opts.Opacity, "Multiplies the alpha of all paths by a value between 0 (exclusive) and 1")
+	flag.StringVar(&background, "background", background, "Fills the background with an (A)RGB value or color name (transparent by default, white for JPEG images)")
+	flag.StringVar(&flatten, "flatten", flatten, "Composites the image over an opaque matte color and writes it without alpha channel")
+	flag.BoolVar(&opts.RTL, "rtl", opts.RTL, "Mirrors auto-mirrored vector images horizontally for right-to-left layouts")
+	flag.StringVar(&scales, "scales", scales, "Generates a version of the image for each of the comma separated factors (adds @<factor>x suffixes)")
+	flag.BoolVar(&opts.IOS, "ios", opts.IOS, "Generates the iOS resolutions of the image (adds @2x and @3x versions by default)")
+	flag.StringVar(&iosSuffixes, "ios-suffixes", iosSuffixes, "Defines the comma separated factors of the -ios versions (default 1x,2x,3x, 1x has no suffix)")
+	flag.StringVar(&iosBase, "ios-base", iosBase, "Defines the iOS factor of the image at -scale, the other -ios versions are scaled relative to it")
+	flag.StringVar(&adaptive, "adaptive", adaptive, "Crops the image to the visible area of an adaptive icon with the given mask (circle|squircle|rounded|square)")
+	flag.StringVar(&layers, "layers", layers, "Draws the comma separated vector images on top of each other into one image, scaled to the size of the first")
+	flag.StringVar(&only, "only", only, "Draws only the paths with the comma separated names (android:name)")
+	flag.StringVar(&exclude, "exclude", exclude, "Leaves out the paths with the comma separated names (android:name)")
+	flag.StringVar(&stdoutFormat, "stdout-format", stdoutFormat, "Encodes the image written to stdout for the output file - (png|base64|datauri, png writes the raw image in any format)")
+	flag.StringVar(&opts.OutputTemplate, "out-template", opts.OutputTemplate, "Names the images with the placeholders {name}, {ext}, {scale} and {density}, such as {name}_{scale}x{ext}")
+	flag.StringVar(&outDir, "out-dir", outDir, "Defines the output directory when converting a directory of vector images")
+	flag.StringVar(&manifestFile, "manifest", manifestFile, "Writes a JSON file describing all generated images")
+	flag.BoolVar(&summaryJSON, "summary-json", summaryJSON, "Prints a JSON summary of the conversion of a single vector image to stdout")
+	flag.IntVar(&jobs, "jobs", jobs, "Defines the number of concurrent conversions when converting a directory (0 uses the number of CPUs)")
+	flag.BoolVar(&opts.Android, "android", opts.Android, "Generates the image for all Android densities (in drawable-mdpi to drawable-xxxhdpi folders)")
+	flag.BoolVar(&opts.Strict, "strict", opts.Strict, "Fails instead of warning about unsupported elements and attributes")
+	flag.BoolVar(&opts.CheckBounds, "check-bounds", opts.CheckBounds, "Warns about paths that lie wholly or partly outside the viewport (fails with -strict)")
+	flag.BoolVar(&dryRun, "dry-run", dryRun, "Checks that the vector images can be converted without writing any files")
+	flag.BoolVar(&watchInput, "watch", watchInput, "Converts the vector images again whenever they change until interrupted")
+	flag.BoolVar(&verbose, "verbose", verbose, "Prints render statistics")
+	flag.BoolVar(&showProgress, "progress", showProgress, "Shows the number of converted files when converting a directory or archive")
+	flag.BoolVar(&failFast, "fail-fast", failFast, "Stops converting a directory or archive at the first failed file instead of converting all others")
+	flag.DurationVar(&timeout, "timeout", timeout, "Fails the conversion of a file of a directory or archive that takes longer than the given duration such as 30s (0 for no limit)")
+	flag.BoolVar(&cachePaths, "cache-paths", cachePaths, "Parses and rasterizes paths that recur in several vector images of a directory or archive only once")
+	flag.BoolVar(&skipUnchanged, "skip-unchanged", skipUnchanged, "Converts only the vector images of a directory or archive that changed since the last conversion with the same options")
+	flag.StringVar(&configFile, "config", configFile, "Reads default values of the options from a JSON file, options given on the command line take precedence")
+	flag.BoolVar(&quiet, "quiet", quiet, "Prints only errors, no warnings, summaries, statistics or progress")
+	flag.BoolVar(&jsonErrors, "json-errors", jsonErrors, "Prints errors to stderr as JSON objects with file, message and detail")
+	flag.BoolVar(&showVersion, "version", false, "Shows the program version")
+	flag.Usage = func() {
+		fmt.Printf("Usage: %s [options] <vector-image-input|directory|archive!entry|-> [<png-image-output>|-]\n", filepath.Base(os.Args[0]))
+		fmt.Printf("       %s [options] -layers <vector-image-input>,... [<png-image-output>]\n", filepath.Base(os.Args[0]))
+		fmt.Printf("       %s inspect <vector-image-input>\n", filepath.Base(os.Args[0]))
+		fmt.Printf("       %s diff [options] <vector-image-input> <vector-image-input|png-image-input>\n", filepath.Base(os.Args[0]))
+		fmt.Printf("       %s render [options] -path <path-data> [-fill <color>] ... <png-image-output>|-\n", filepath.Base(os.Args[0]))
+		fmt.Printf("       %s sprite [options] <vector-image-input|directory>... <png-sheet-output>\n\n", filepath.Base(os.Args[0]))
+		flag.PrintDefaults()
+		fmt.Println()
+	}
+	flag.Parse()
+	if configFile != "" {
+		if err := applyConfig(flag.CommandLine, configFile); err != nil {
+			usageExit(fmt.Sprintf("Cannot apply config file \"%s\"", configFile), err)
+		}
+	}
+
+	if quiet {
+		verbose = false
+		showProgress = false
+	}
+
+	if showVersion {
+		fmt.Println(version)
+		os.Exit(0)
+	}
+
+	if opts.Opacity <= 0 || opts.Opacity > 1 {
+		usageExit(fmt.Sprintf("Invalid opacity %g (must be greater than 0 and at most 1)", opts.Opacity), nil)
+	}
+
+	if format != "" {
+		f, err := vectopng.ParseFormat(format)
+		if err != nil {
+			usageExit("Invalid format", err)
+		}
+		opts.Format = f
+	}
+
+	switch colorProfile {
+	case "srgb":
+	case "none":
+		opts.NoColorProfile = true
+	default:
+		profile, err := os.ReadFile(colorProfile)
+		if err != nil {
+			fileErrorExit(colorProfile, "Cannot read color profile", err)
+		}
+		opts.ColorProfile = profile
+	}
+
+	if gradientInterpolation != "" {
+		interpolation, err := vectopng.ParseInterpolation(gradientInterpolation)
+		if err != nil {
+			usageExit("Invalid gradient interpolation", err)
+		}
+		opts.GradientInterpolation = interpolation
+	}
+
+	if adaptive != "" {
+		mask, err := vectopng.ParseMask(adaptive)
+		if err != nil {
+			usageExit("Invalid adaptive icon mask", err)
+		}
+		opts.AdaptiveMask = mask
+	}
+
+	if icoSizes != "" {
+		for _, size := range strings.Split(icoSizes, ",") {
+			n, err := strconv.Atoi(strings.TrimSpace(size))
+			if err != nil || n < 1 || n > 256 {
+				usageExit(fmt.Sprintf("Invalid ICO size \"%s\" (must be between 1 and 256)", size), nil)
+			}
+			opts.ICOSizes = append(opts.ICOSizes, n)
+		}
+	}
+
+	for _, scale := range splitNames(scales) {
+		f, err := strconv.ParseFloat(scale, 64)
+		if err != nil || f <= 0 {
+			usageExit(fmt.Sprintf("Invalid scale \"%s\" (must be greater than 0)", scale), nil)
+		}
+		opts.Scales = append(opts.Scales, f)
+	}
+	if len(opts.Scales) > 0 && opts.IOS {
+		usageExit("Use either -scales or -ios", nil)
+	}
+	for _, suffix := range splitNames(iosSuffixes) {
+		opts.IOSScales = append(opts.IOSScales, parseIOSFactor(suffix))
+	}
+	opts.IOSBase = parseIOSFactor(iosBase)
+
+	opts.Only = splitNames(only)
+	opts.Exclude = splitNames(exclude)
+
+	if layers != "" && flag.NArg() <= 1 {
+		for _, layer := range strings.Split(layers, ",") {
+			vectorFiles = append(vectorFiles, strings.TrimSpace(layer))
+		}
+		pngFile = pathWithoutExtension(vectorFiles[0]) + outputExtension(opts)
+		if flag.NArg() == 1 {
+			pngFile = flag.Arg(0)
+		}
+	} else if layers != "" {
+		usageExit("Only the output image can be given besides -layers", nil)
+	} else if flag.NArg() == 1 {
+		vectorFiles = []string{flag.Arg(0)}
+		pngFile = pathWithoutExtension(flag.Arg(0)) + outputExtension(opts)
+		if archive, entry, ok := splitArchivePath(flag.Arg(0)); ok {
+			pngFile = archiveOutput(archive, path.Base(entry), "", outputExtension(opts))
+		} else if flag.Arg(0) == "-" {
+			pngFile = "-"
+		}
+	} else if flag.NArg() == 2 {
+		vectorFiles = []string{flag.Arg(0)}
+		pngFile = flag.Arg(1)
+	} else {
+		printError("Input vector image parameter is missing", nil)
+		flag.Usage()
+		os.Exit(exitUsage)
+	}
+
+	switch stdoutFormat {
+	case "png", "base64", "datauri":
+	default:
+		usageExit(fmt.Sprintf("Invalid stdout format \"%s\"", stdoutFormat), nil)
+	}
+	if pngFile == "-" && (opts.IOS || opts.Android || len(opts.Scales) > 0) {
+		usageExit("The -ios, -android and -scales versions cannot be written to stdout", nil)
+	}
+	if pngFile == "-" && summaryJSON {
+		usageExit("The -summary-json summary cannot be printed if the image is written to stdout", nil)
+	}
+
+	if resDir != "" {
+		resColors, resStrings := resourceFiles(resDir, theme)
+		colorsFiles = append(resColors, colorsFiles...)
+		stringsFiles = append(resStrings, stringsFiles...)
+	} else if theme != "" {
+		usageExit("Use -theme together with -res-dir", nil)
+	}
+
+	// The color definitions are complete from here on and only read by the
+	// conversions, which may run concurrently.
+	opts.Colors = buildColorDefs(colorDefs, colorsFiles)
+	opts.Strings = buildStringDefs(stringsFiles)
+	opts.DefaultColor = parseColorOption(defaultColor, "default color", opts.Colors)
+	opts.Background = parseColorOption(background, "background color", opts.Colors)
+	opts.Flatten = parseColorOption(flatten, "flatten color", opts.Colors)
+	opts.Tint = parseColorOption(tint, "tint color", opts.Colors)
+	if cachePaths {
+		opts.PathCache = &vectopng.PathCache{}
+	}
+
+	// convert converts the input and reports whether it succeeded. Errors do
+	// not exit, so that watching can continue after a failed conversion.
+	var convert func() bool
+	// finishBatch prints the summary of a batch conversion and writes its
+	// manifest.
+	finishBatch := func(summary batchSummary) bool {
+		if dryRun {
+			printInfo("%d ok, %d skipped, %d failed%s\n", summary.Converted, summary.Skipped, summary.Failed, aborted(summary))
+		} else {
+			msg := fmt.Sprintf("%d converted", summary.Converted)
+			if skipUnchanged {
+				msg += fmt.Sprintf(", %d unchanged", summary.Unchanged)
+			}
+			printInfo("%s, %d skipped, %d failed%s\n", msg, summary.Skipped, summary.Failed, aborted(summary))
+		}
+		if verbose && opts.PathCache != nil {
+			fmt.Fprintf(os.Stderr, "path cache: %d paths reused, %d rasterized paths reused\n", opts.PathCache.Hits(), opts.PathCache.TileHits())
+		}
+		if manifestFile != "" && !dryRun {
+			writeManifest(manifestFile, summary.Manifest)
+		}
+		return summary.Failed == 0
+	}
+	batch := batchOptions{Jobs: jobs, Verbose: verbose, DryRun: dryRun, Progress: showProgress, FailFast: failFast, Timeout: timeout, StdoutFormat: stdoutFormat}
+	archive, entry, isArchive := splitArchivePath(vectorFiles[0])
+	if info, err := os.Stat(vectorFiles[0]); err == nil && info.IsDir() && layers == "" {
+		if flag.NArg() == 2 {
+			usageExit("Use -out-dir to define the output directory of a directory conversion", nil)
+		}
+		if skipUnchanged {
+			batch.CacheFile = filepath.Join(vectorFiles[0], cacheFileName)
+			if outDir != "" {
+				batch.CacheFile = filepath.Join(outDir, cacheFileName)
+			}
+		}
+		convert = func() bool {
+			summary, err := convertDir(vectorFiles[0], outDir, batch, opts)
+			if err != nil {
+				printFileError(vectorFiles[0], "Cannot read directory", err)
+				return false
+			}
+			return finishBatch(summary)
+		}
+	} else if isArchive && (entry == "" || strings.ContainsAny(entry, "*?[")) && layers == "" {
+		if flag.NArg() == 2 {
+			usageExit("Use -out-dir to define the output directory of an archive conversion", nil)
+		}
+		if skipUnchanged {
+			batch.CacheFile = filepath.Join(outDir, cacheFileName)
+			if outDir == "" {
+				batch.CacheFile = filepath.Join(filepath.Dir(archive), cacheFileName)
+			}
+		}
+		convert = func() bool {
+			files, err := archiveEntries(archive, entry)
+			if err != nil {
+				printFileError(archive, "Cannot read archive", err)
+				return false
+			}
+			pngFiles := make([]string, len(files))
+			for i, file := range files {
+				_, fileEntry, _ := splitArchivePath(file)
+				pngFiles[i] = archiveOutput(archive, fileEntry, outDir, outputExtension(opts))
+			}
+			return finishBatch(convertFiles(files, pngFiles, batch, opts))
+		}
+	} else {
+		convert = func() bool {
+			conv := convertLayers(vectorFiles, pngFile, batch, opts)
+			for _, warning := range conv.Warnings {
+				printWarning(warning)
+			}
+			if dryRun {
+				printResult(conv)
+			} else if errors.Is(conv.Err, vectopng.ErrNotVector) {
+				printFileError(conv.Source, "Not a valid Android vector drawable", nil)
+			} else if conv.Err != nil {
+				printFileError(conv.Source, "Cannot convert", conv.Err)
+			}
+			if conv.Err != nil {
+				return false
+			}
+			if verbose {
+				printStats(conv.Source, conv)
+			}
+			if manifestFile != "" && !dryRun {
+				writeManifest(manifestFile, newManifestEntries(conv))
+			}
+			if summaryJSON {
+				printSummary(conv)
+			}
+			return true
+		}
+	}
+
+	ok := convert()
+	if watchInput {
+		if err := watch(vectorFiles, convert); err != nil {
+			errorExit("Cannot watch for changes", err)
+		}
+	}
+	if !ok {
+		os.Exit(exitFailure)
+	}
+}
+
+// aborted describes the files that -fail-fast left out for the summary.
+func aborted(summary batchSummary) string {
+	if summary.Aborted == 0 {
+		return ""
+	}
+	return fmt.Sprintf(", %d not converted after the first failure", summary.Aborted)
+}
+
+// buildColorDefs adds the colors of the colors files to the colors defined
+// on the command line. The colors files are parsed only once, even when
+// converting a whole directory.
+func buildColorDefs(colorDefs vectopng.ColorDefs, colorsFiles []string) vectopng.ColorDefs {
+	if len(colorsFiles) == 0 {
+		return colorDefs
+	}
+	var colorsData [][]byte
+	for _, colorsFile := range colorsFiles {
+		data, err := os.ReadFile(colorsFile)
+		if err != nil {
+			fileErrorExit(colorsFile, "Cannot read colors file", err)
+		}
+		colorsData = append(colorsData, data)
+	}
+	if err := vectopng.ParseColorsMerged(colorsData, colorDefs); err != nil {
+		errorExit(fmt.Sprintf("Cannot parse colors files %s", strings.Join(colorsFiles, ", ")), err)
+	}
+	return colorDefs
+}
+
+// buildStringDefs parses the strings files once like buildColorDefs.
+func buildStringDefs(stringsFiles []string) vectopng.StringDefs {
+	stringDefs := make(vectopng.StringDefs)
+	for _, stringsFile := range stringsFiles {
+		data, err := os.ReadFile(stringsFile)
+		if err != nil {
+			fileErrorExit(stringsFile, "Cannot read strings file", err)
+		}
+		if err := vectopng.ParseStrings(data, stringDefs); err != nil {
+			fileErrorExit(stringsFile, "Cannot parse strings file", err)
+		}
+	}
+	return stringDefs
+}
+
+// parseColorOption parses the value of a color option and returns nil if it
+// is empty.
+func parseColorOption(value string, name string, colorDefs vectopng.ColorDefs) color.Color {
+	if value == "" {
+		return nil
+	}
+	c, err := vectopng.ParseColor(value, colorDefs)
+	if err != nil {
+		usageExit("Invalid "+name, err)
+	}
+	return c
+}
+
+// outputExtension returns the file extension of images derived from the
+// vector file name.
+func outputExtension(opts vectopng.Options) string {
+	if opts.Format != "" {
+		return "." + string(opts.Format)
+	}
+	return ".png"
+}
+
+func pathWithoutExtension(p string) string {
+	return strings.TrimSuffix(p, filepath.Ext(p))
+}
+
+// writeFile writes a file like os.WriteFile, creating missing parent
+// directories first like vectopng.Save does for the images.
+func writeFile(name string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(name), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(name, data, 0o644)
+}
+
+// parseIOSFactor parses an iOS factor such as 2x, the x is optional.
+func parseIOSFactor(s string) float64 {
+	f, err := strconv.ParseFloat(strings.TrimSuffix(s, "x"), 64)
+	if err != nil || f <= 0 {
+		usageExit(fmt.Sprintf("Invalid iOS factor \"%s\" (must be greater than 0, such as 2x)", s), nil)
+	}
+	return f
+}
+
+// splitNames splits a comma separated list of names, ignoring empty ones.
+func splitNames(list string) []string {
+	var names []string
+	for _, name := range strings.Split(list, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
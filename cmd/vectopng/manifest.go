@@ -0,0 +1,93 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+)
+
+// manifestEntry describes one generated image in the manifest file.
+type manifestEntry struct {
+	Source   string   `json:"source"`
+	Output   string   `json:"output"`
+	Width    int      `json:"width"`
+	Height   int      `json:"height"`
+	Scale    float64  `json:"scale"`
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+func newManifestEntries(conv conversion) []manifestEntry {
+	entries := make([]manifestEntry, len(conv.Outputs))
+	for i, output := range conv.Outputs {
+		entries[i] = manifestEntry{
+			Source:   conv.Source,
+			Output:   output.File,
+			Width:    output.Width,
+			Height:   output.Height,
+			Scale:    output.Scale,
+			Warnings: conv.Warnings,
+		}
+	}
+	return entries
+}
+
+func writeManifest(manifestFile string, entries []manifestEntry) {
+	if entries == nil {
+		entries = []manifestEntry{}
+	}
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(entries); err != nil {
+		errorExit("Cannot encode manifest", err)
+	}
+	if err := writeFile(manifestFile, buf.Bytes()); err != nil {
+		fileErrorExit(manifestFile, "Cannot write manifest file", err)
+	}
+}
+
+// conversionSummary describes a single conversion for -summary-json.
+type conversionSummary struct {
+	Input        string          `json:"input"`
+	Outputs      []summaryOutput `json:"outputs"`
+	PathsDrawn   int             `json:"pathsDrawn"`
+	PathsSkipped int             `json:"pathsSkipped"`
+	Warnings     []string        `json:"warnings"`
+}
+
+// summaryOutput is one image written by a single conversion.
+type summaryOutput struct {
+	File   string  `json:"file"`
+	Width  int     `json:"width"`
+	Height int     `json:"height"`
+	Scale  float64 `json:"scale"`
+}
+
+// printSummary prints the JSON summary of the conversion to stdout.
+func printSummary(conv conversion) {
+	summary := conversionSummary{
+		Input:        conv.Source,
+		Outputs:      []summaryOutput{},
+		PathsDrawn:   conv.Stats.PathsDrawn,
+		PathsSkipped: conv.Stats.PathsSkipped,
+		Warnings:     conv.Warnings,
+	}
+	if summary.Warnings == nil {
+		summary.Warnings = []string{}
+	}
+	for _, output := range conv.Outputs {
+		summary.Outputs = append(summary.Outputs, summaryOutput{
+			File:   output.File,
+			Width:  output.Width,
+			Height: output.Height,
+			Scale:  output.Scale,
+		})
+	}
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(summary); err != nil {
+		errorExit("Cannot encode summary", err)
+	}
+}
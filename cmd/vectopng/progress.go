@@ -0,0 +1,58 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// progressInterval is the time between two progress lines if stderr is not
+// a terminal.
+const progressInterval = 2 * time.Second
+
+// progress shows the number of finished conversions on stderr. On a
+// terminal the line is updated in place, otherwise a line is printed at most
+// every progressInterval. It is safe for concurrent use.
+type progress struct {
+	mu      sync.Mutex
+	total   int
+	done    int
+	tty     bool
+	printed time.Time
+}
+
+func newProgress(total int) *progress {
+	p := &progress{total: total, printed: time.Now()}
+	if info, err := os.Stderr.Stat(); err == nil {
+		p.tty = info.Mode()&os.ModeCharDevice != 0
+	}
+	return p
+}
+
+// add counts a finished conversion.
+func (p *progress) add() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.done++
+	if p.tty {
+		fmt.Fprintf(os.Stderr, "\r%s", p.line())
+	} else if time.Since(p.printed) >= progressInterval || p.done == p.total {
+		fmt.Fprintln(os.Stderr, p.line())
+		p.printed = time.Now()
+	}
+}
+
+// finish ends the line updated in place so that other output follows on a
+// new line.
+func (p *progress) finish() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.tty && p.done > 0 {
+		fmt.Fprintln(os.Stderr)
+	}
+}
+
+func (p *progress) line() string {
+	return fmt.Sprintf("%d/%d files (%d%%)", p.done, p.total, 100*p.done/p.total)
+}
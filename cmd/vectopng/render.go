@@ -0,0 +1,130 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"perron2.ch/vectopng"
+)
+
+// pathList collects the paths of the render command. -path starts a new
+// path, the other flags set an attribute of the last path.
+type pathList []vectopng.Path
+
+func (pl *pathList) String() string {
+	return strconv.Itoa(len(*pl))
+}
+
+// last returns the last path or an error if no path was given yet.
+func (pl *pathList) last(flagName string) (*vectopng.Path, error) {
+	if len(*pl) == 0 {
+		return nil, fmt.Errorf("-%s must follow a -path", flagName)
+	}
+	return &(*pl)[len(*pl)-1], nil
+}
+
+// pathFlag is a flag setting an attribute of the last path of a pathList,
+// or of a new path if newPath is set.
+type pathFlag struct {
+	paths   *pathList
+	name    string
+	newPath bool
+	set     func(path *vectopng.Path, value string) error
+}
+
+func (pf *pathFlag) String() string {
+	return ""
+}
+
+func (pf *pathFlag) Set(value string) error {
+	if pf.newPath {
+		*pf.paths = append(*pf.paths, vectopng.Path{})
+	}
+	path, err := pf.paths.last(pf.name)
+	if err != nil {
+		return err
+	}
+	return pf.set(path, value)
+}
+
+// render draws path data given on the command line, for trying path syntax
+// without writing a vector drawable. A path without fill and stroke color is
+// filled black.
+func render(args []string) {
+	flags := flag.NewFlagSet("render", flag.ExitOnError)
+	opts := vectopng.Options{Scale: 1.0, Opacity: 1.0}
+	colorDefs := make(vectopng.ColorDefs)
+	var paths pathList
+	width := 24.0
+	height := 24.0
+	background := ""
+	pathAttr := func(name string, usage string, set func(path *vectopng.Path, value string) error) {
+		flags.Var(&pathFlag{paths: &paths, name: name, newPath: name == "path", set: set}, name, usage)
+	}
+	pathAttr("path", "Adds a path with the given `data` (can be repeated)", func(path *vectopng.Path, value string) error {
+		path.PathData = value
+		return nil
+	})
+	pathAttr("fill", "Fills the last path with the `color`, an (A)RGB value or color name", func(path *vectopng.Path, value string) error {
+		path.FillColor = value
+		return nil
+	})
+	pathAttr("stroke", "Strokes the last path with the `color`, an (A)RGB value or color name", func(path *vectopng.Path, value string) error {
+		path.StrokeColor = value
+		return nil
+	})
+	pathAttr("stroke-width", "Defines the stroke `width` of the last path (default 1 if -stroke is given)", func(path *vectopng.Path, value string) error {
+		w, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return err
+		}
+		path.StrokeWidth = w
+		return nil
+	})
+	flags.Float64Var(&width, "width", width, "Defines the width of the drawable and its viewport in dp")
+	flags.Float64Var(&height, "height", height, "Defines the height of the drawable and its viewport in dp")
+	flags.Var(&colorDefs, "color", "Defines an (A)RGB value for a color name (name=#(a)rgb|(aa)rrggbb)")
+	flags.Var(&scaleValue{&opts.Scale, &opts.ScaleY}, "scale", "Scales the image by the given `factor` or by separate horizontal and vertical factors such as 2x1.5")
+	flags.StringVar(&background, "background", background, "Fills the background with an (A)RGB value or color name")
+	flags.Usage = func() {
+		fmt.Printf("Usage: %s render [options] -path <path-data> [-fill <color>] ... <png-image-output>|-\n\n", filepath.Base(os.Args[0]))
+		flags.PrintDefaults()
+		fmt.Println()
+	}
+	flags.Parse(args)
+	if flags.NArg() != 1 || len(paths) == 0 {
+		printError("A -path and the output image are required", nil)
+		flags.Usage()
+		os.Exit(exitUsage)
+	}
+	if width <= 0 || height <= 0 {
+		usageExit(fmt.Sprintf("Invalid size %gx%g (must be greater than 0)", width, height), nil)
+	}
+	for i := range paths {
+		if paths[i].FillColor == "" && paths[i].StrokeColor == "" {
+			paths[i].FillColor = "#000000"
+		}
+		if paths[i].StrokeColor != "" && paths[i].StrokeWidth == 0 {
+			paths[i].StrokeWidth = 1
+		}
+	}
+	opts.Colors = colorDefs
+	opts.Background = parseColorOption(background, "background color", opts.Colors)
+	opts.Warn = printWarning
+
+	c, err := vectopng.ConvertPaths(width, height, paths, opts)
+	if err != nil {
+		errorExit("Cannot render paths", err)
+	}
+	if pngFile := flags.Arg(0); pngFile == "-" {
+		_, err = writeStdout(c, "png", opts)
+	} else {
+		_, err = vectopng.Save(c, pngFile, opts)
+	}
+	if err != nil {
+		errorExit("Cannot write image", err)
+	}
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// Exit codes of the program. Usage errors exit like the flag package does.
+const (
+	exitFailure = 1
+	exitUsage   = 2
+)
+
+// quiet suppresses all output except errors and the images written to
+// stdout.
+var quiet bool
+
+// jsonErrors prints errors as JSON objects, one per line.
+var jsonErrors bool
+
+// errorReport is an error printed with jsonErrors.
+type errorReport struct {
+	File    string `json:"file,omitempty"`
+	Message string `json:"message"`
+	Detail  string `json:"detail,omitempty"`
+}
+
+// errorExit prints the error and exits with exitFailure.
+func errorExit(msg string, err error) {
+	printError(msg, err)
+	os.Exit(exitFailure)
+}
+
+// fileErrorExit prints the error about a file and exits with exitFailure.
+func fileErrorExit(file string, msg string, err error) {
+	printFileError(file, msg, err)
+	os.Exit(exitFailure)
+}
+
+// usageExit prints the error about the command line and exits with
+// exitUsage.
+func usageExit(msg string, err error) {
+	printError(msg, err)
+	os.Exit(exitUsage)
+}
+
+func printError(msg string, err error) {
+	printFileError("", msg, err)
+}
+
+// printFileError prints an error to stderr. The file is quoted after the
+// message and the error follows in parentheses unless they are empty.
+func printFileError(file string, msg string, err error) {
+	report := errorReport{File: file, Message: msg}
+	if err != nil {
+		report.Detail = err.Error()
+	}
+	if jsonErrors {
+		data, _ := json.Marshal(report)
+		fmt.Fprintln(os.Stderr, string(data))
+		return
+	}
+	text := "ERROR: " + msg
+	if file != "" {
+		text += fmt.Sprintf(" \"%s\"", file)
+	}
+	if err != nil {
+		text += fmt.Sprintf(" (%v)", err)
+	}
+	fmt.Fprintln(os.Stderr, text)
+}
+
+func printWarning(msg string) {
+	if !quiet {
+		fmt.Fprintln(os.Stderr, "WARNING: "+msg)
+	}
+}
+
+// printInfo prints a message to stdout unless quiet is set.
+func printInfo(format string, args ...any) {
+	if !quiet {
+		fmt.Printf(format, args...)
+	}
+}
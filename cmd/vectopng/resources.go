@@ -0,0 +1,41 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// resourceFiles returns the colors.xml and strings.xml files of the values
+// folder of an Android res directory. If theme is not empty, such as night,
+// the files of the values-<theme> folder follow, so that they override the
+// default values. A missing theme folder is reported as warning.
+func resourceFiles(resDir string, theme string) (colorsFiles []string, stringsFiles []string) {
+	if info, err := os.Stat(resDir); err != nil {
+		fileErrorExit(resDir, "Cannot read res directory", err)
+	} else if !info.IsDir() {
+		fileErrorExit(resDir, "Not a directory", nil)
+	}
+	folders := []string{"values"}
+	if theme != "" {
+		folder := "values-" + theme
+		if info, err := os.Stat(filepath.Join(resDir, folder)); err != nil || !info.IsDir() {
+			printWarning(fmt.Sprintf("%s has no %s folder, only the default values are used", resDir, folder))
+		}
+		folders = append(folders, folder)
+	}
+	for _, folder := range folders {
+		for _, name := range []string{"colors.xml", "strings.xml"} {
+			file := filepath.Join(resDir, folder, name)
+			if _, err := os.Stat(file); err != nil {
+				continue
+			}
+			if name == "colors.xml" {
+				colorsFiles = append(colorsFiles, file)
+			} else {
+				stringsFiles = append(stringsFiles, file)
+			}
+		}
+	}
+	return colorsFiles, stringsFiles
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"image"
+	"image/png"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"perron2.ch/vectopng"
+)
+
+// atlas describes a sprite sheet in the JSON atlas file.
+type atlas struct {
+	Image   string        `json:"image"`
+	Width   int           `json:"width"`
+	Height  int           `json:"height"`
+	Sprites []atlasSprite `json:"sprites"`
+}
+
+// atlasSprite is the rectangle of one rendered drawable in the sheet.
+type atlasSprite struct {
+	Name   string `json:"name"`
+	Source string `json:"source"`
+	X      int    `json:"x"`
+	Y      int    `json:"y"`
+	Width  int    `json:"width"`
+	Height int    `json:"height"`
+}
+
+// sprite renders vector drawables at a common scale and packs them into one
+// PNG sprite sheet, described by a JSON atlas file next to it.
+func sprite(args []string) {
+	flags := flag.NewFlagSet("sprite", flag.ExitOnError)
+	opts := vectopng.Options{Scale: 1.0, Opacity: 1.0}
+	colorDefs := make(vectopng.ColorDefs)
+	var colorsFiles stringList
+	var stringsFiles stringList
+	columns := 0
+	spacing := 0
+	atlasFile := ""
+	cachePaths := false
+	flags.Var(&colorDefs, "color", "Defines an (A)RGB value for a color name (name=#(a)rgb|(aa)rrggbb)")
+	flags.Var(&colorsFiles, "colors", "Defines an Android color resource file to be parsed for color definitions (can be repeated, later files override earlier ones)")
+	flags.Var(&stringsFiles, "strings", "Defines an Android string resource file for string references in pathData and colors (can be repeated, later files override earlier ones)")
+	flags.Var(&scaleValue{&opts.Scale, &opts.ScaleY}, "scale", "Scales the drawables by the given `factor` or by separate horizontal and vertical factors such as 2x1.5")
+	flags.IntVar(&columns, "columns", columns, "Places the sprites in a grid with the given number of columns (0 packs them in rows automatically)")
+	flags.IntVar(&spacing, "spacing", spacing, "Defines the number of transparent pixels between the sprites")
+	flags.StringVar(&atlasFile, "atlas", atlasFile, "Defines the JSON atlas file describing the sprites (default: the sheet file with a .json extension)")
+	flags.BoolVar(&cachePaths, "cache-paths", cachePaths, "Parses and rasterizes paths that recur in several drawables only once")
+	flags.Usage = func() {
+		fmt.Printf("Usage: %s sprite [options] <vector-image-input|directory>... <png-sheet-output>\n\n", filepath.Base(os.Args[0]))
+		flags.PrintDefaults()
+		fmt.Println()
+	}
+	flags.Parse(args)
+	if flags.NArg() < 2 {
+		printError("Vector images and the sheet output are required", nil)
+		flags.Usage()
+		os.Exit(exitUsage)
+	}
+	if columns < 0 || spacing < 0 {
+		usageExit("Invalid -columns or -spacing (must not be negative)", nil)
+	}
+	sheetFile := flags.Arg(flags.NArg() - 1)
+	if atlasFile == "" {
+		atlasFile = pathWithoutExtension(sheetFile) + ".json"
+	}
+	opts.Colors = buildColorDefs(colorDefs, colorsFiles)
+	opts.Strings = buildStringDefs(stringsFiles)
+	if cachePaths {
+		opts.PathCache = &vectopng.PathCache{}
+	}
+
+	var files []string
+	for _, input := range flags.Args()[:flags.NArg()-1] {
+		files = append(files, spriteFiles(input)...)
+	}
+	var images []image.Image
+	var sprites []atlasSprite
+	for _, file := range files {
+		xmlData, err := os.ReadFile(file)
+		if err != nil {
+			fileErrorExit(file, "Cannot read", err)
+		}
+		fileOpts := opts
+		fileOpts.Warn = func(warning string) {
+			printWarning(fmt.Sprintf("%s: %s", file, warning))
+		}
+		c, err := vectopng.Convert(xmlData, fileOpts)
+		if errors.Is(err, vectopng.ErrNotVector) {
+			continue
+		} else if err != nil {
+			fileErrorExit(file, "Cannot convert", err)
+		}
+		images = append(images, vectopng.Rasterize(c, fileOpts))
+		name := pathWithoutExtension(filepath.Base(file))
+		sprites = append(sprites, atlasSprite{Name: name, Source: file})
+	}
+	if len(images) == 0 {
+		errorExit("No vector drawables found", nil)
+	}
+
+	sheet, rects := vectopng.SpriteSheet(images, columns, spacing)
+	for i, rect := range rects {
+		sprites[i].X, sprites[i].Y = rect.Min.X, rect.Min.Y
+		sprites[i].Width, sprites[i].Height = rect.Dx(), rect.Dy()
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, sheet); err != nil {
+		errorExit("Cannot encode sprite sheet", err)
+	}
+	if err := writeFile(sheetFile, buf.Bytes()); err != nil {
+		fileErrorExit(sheetFile, "Cannot write sprite sheet", err)
+	}
+
+	size := sheet.Bounds().Size()
+	sheetAtlas := atlas{Image: filepath.Base(sheetFile), Width: size.X, Height: size.Y, Sprites: sprites}
+	buf.Reset()
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(sheetAtlas); err != nil {
+		errorExit("Cannot encode atlas", err)
+	}
+	if err := writeFile(atlasFile, buf.Bytes()); err != nil {
+		fileErrorExit(atlasFile, "Cannot write atlas file", err)
+	}
+	printInfo("%d sprites, %dx%d px\n", len(sprites), size.X, size.Y)
+}
+
+// spriteFiles returns the input file or the XML files of an input directory
+// in lexical order.
+func spriteFiles(input string) []string {
+	info, err := os.Stat(input)
+	if err != nil {
+		fileErrorExit(input, "Cannot read", err)
+	}
+	if !info.IsDir() {
+		return []string{input}
+	}
+	var files []string
+	err = filepath.WalkDir(input, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() && strings.EqualFold(filepath.Ext(p), ".xml") {
+			files = append(files, p)
+		}
+		return nil
+	})
+	if err != nil {
+		fileErrorExit(input, "Cannot read directory", err)
+	}
+	return files
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"os"
+
+	"github.com/tdewolff/canvas"
+	"perron2.ch/vectopng"
+)
+
+// writeStdout writes the canvas as a single image to stdout. The encoding
+// png writes the raw image in any format, base64 its base64 encoding and
+// datauri a data URI with the media type of the format, each followed by a
+// newline.
+func writeStdout(c *canvas.Canvas, encoding string, opts vectopng.Options) (vectopng.Output, error) {
+	var buf bytes.Buffer
+	output, err := vectopng.Write(&buf, c, opts.Format, opts)
+	if err != nil {
+		return vectopng.Output{}, err
+	}
+	output.File = "-"
+
+	data := buf.Bytes()
+	switch encoding {
+	case "base64":
+		data = []byte(base64.StdEncoding.EncodeToString(data) + "\n")
+	case "datauri":
+		format := opts.Format
+		if format == "" {
+			format = vectopng.FormatPNG
+		}
+		data = []byte(fmt.Sprintf("data:%s;base64,%s\n", format.MIMEType(), base64.StdEncoding.EncodeToString(data)))
+	}
+	if _, err := os.Stdout.Write(data); err != nil {
+		return vectopng.Output{}, err
+	}
+	return output, nil
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// watchDelay is the time without further changes after which the input is
+// converted again, so that successive writes are combined.
+const watchDelay = 100 * time.Millisecond
+
+// watch waits for changes of the input files or of the vector files of an
+// input directory and calls convert each time. It runs until the program is
+// interrupted or the changes can no longer be watched.
+func watch(inputs []string, convert func() bool) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	defer watcher.Close()
+
+	// The directories of files are watched rather than the files, since
+	// editors often replace a file instead of writing to it.
+	files := make(map[string]bool)
+	var dirs []string
+	for _, input := range inputs {
+		if archive, _, ok := splitArchivePath(input); ok {
+			input = archive
+		}
+		input = filepath.Clean(input)
+		if info, err := os.Stat(input); err == nil && info.IsDir() {
+			if err := watchTree(watcher, input); err != nil {
+				return err
+			}
+			dirs = append(dirs, input)
+			continue
+		}
+		files[input] = true
+		if err := watcher.Add(filepath.Dir(input)); err != nil {
+			return err
+		}
+	}
+	// Other files in the directories of input files are ignored.
+	relevant := func(event fsnotify.Event) bool {
+		name := filepath.Clean(event.Name)
+		if files[name] {
+			return true
+		}
+		for _, dir := range dirs {
+			if !strings.HasPrefix(name, dir+string(filepath.Separator)) {
+				continue
+			}
+			if info, err := os.Stat(name); err == nil && info.IsDir() {
+				// New directories below an input directory are watched
+				// as well.
+				if event.Has(fsnotify.Create) {
+					watchTree(watcher, name)
+				}
+				return false
+			}
+			return strings.EqualFold(filepath.Ext(name), ".xml")
+		}
+		return false
+	}
+
+	input := strings.Join(inputs, ",")
+	printInfo("Watching %s for changes\n", input)
+	timer := time.NewTimer(watchDelay)
+	timer.Stop()
+	for {
+		select {
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return nil
+			}
+			if event.Has(fsnotify.Chmod) || !relevant(event) {
+				continue
+			}
+			timer.Reset(watchDelay)
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return nil
+			}
+			return err
+		case <-timer.C:
+			printInfo("[%s] %s changed\n", time.Now().Format(time.TimeOnly), input)
+			convert()
+		}
+	}
+}
+
+// watchTree adds the directory and all directories below it to the watcher.
+func watchTree(watcher *fsnotify.Watcher, dir string) error {
+	return filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return watcher.Add(p)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,244 @@
+package vectopng
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"image/color"
+	"math"
+	"regexp"
+	"strings"
+)
+
+// ErrUnresolvedColor is returned by ParseColor if a color reference cannot
+// be resolved.
+var ErrUnresolvedColor = errors.New("unresolved color reference")
+
+var colorPattern = regexp.MustCompile(`^(#?)([0-9a-fA-F]+)$`)
+
+// androidColors contains the colors defined in android.R.color.
+var androidColors = map[string]color.Color{
+	"background_dark":   color.NRGBA{0x00, 0x00, 0x00, 0xff},
+	"background_light":  color.NRGBA{0xff, 0xff, 0xff, 0xff},
+	"black":             color.NRGBA{0x00, 0x00, 0x00, 0xff},
+	"darker_gray":       color.NRGBA{0xaa, 0xaa, 0xaa, 0xff},
+	"holo_blue_bright":  color.NRGBA{0x00, 0xdd, 0xff, 0xff},
+	"holo_blue_dark":    color.NRGBA{0x00, 0x99, 0xcc, 0xff},
+	"holo_blue_light":   color.NRGBA{0x33, 0xb5, 0xe5, 0xff},
+	"holo_green_dark":   color.NRGBA{0x66, 0x99, 0x00, 0xff},
+	"holo_green_light":  color.NRGBA{0x99, 0xcc, 0x00, 0xff},
+	"holo_orange_dark":  color.NRGBA{0xff, 0x88, 0x00, 0xff},
+	"holo_orange_light": color.NRGBA{0xff, 0xbb, 0x33, 0xff},
+	"holo_purple":       color.NRGBA{0xaa, 0x66, 0xcc, 0xff},
+	"holo_red_dark":     color.NRGBA{0xcc, 0x00, 0x00, 0xff},
+	"holo_red_light":    color.NRGBA{0xff, 0x44, 0x44, 0xff},
+	"transparent":       color.NRGBA{0x00, 0x00, 0x00, 0x00},
+	"white":             color.NRGBA{0xff, 0xff, 0xff, 0xff},
+}
+
+type colorDef struct {
+	Name  string `xml:"name,attr"`
+	Color string `xml:",chardata"`
+}
+
+type colorDefsArray struct {
+	Colors []colorDef `xml:"color"`
+}
+
+// ColorDefs maps color names to colors. It implements flag.Value so that
+// colors can be defined on the command line as name=#(a)rgb|(aa)rrggbb.
+type ColorDefs map[string]color.Color
+
+func (cd *ColorDefs) String() string {
+	return ""
+}
+
+func (cd *ColorDefs) Set(value string) error {
+	parts := strings.SplitN(value, "=", 2)
+	if len(parts) != 2 {
+		return fmt.Errorf("invalid color definition \"%s\"", value)
+	}
+	color, err := ParseColor(strings.TrimSpace(parts[1]), nil)
+	if err != nil {
+		return fmt.Errorf("invalid color definition \"%s\": %w", value, err)
+	}
+
+	name := strings.TrimSpace(parts[0])
+	(*cd)[name] = color
+	return nil
+}
+
+// ParseColors parses an Android color resource file and adds its colors to
+// colorDefs as @color/name. Colors may reference each other in any order.
+// Colors that cannot be resolved are reported in the returned error, all
+// other colors are still added.
+func ParseColors(colorsData []byte, colorDefs ColorDefs) error {
+	return ParseColorsMerged([][]byte{colorsData}, colorDefs)
+}
+
+// ParseColorsMerged parses several Android color resource files like
+// ParseColors. A color defined in a later file overrides a color of the same
+// name in an earlier file, references are resolved across all files.
+func ParseColorsMerged(colorsData [][]byte, colorDefs ColorDefs) error {
+	var colors []colorDef
+	indexes := make(map[string]int)
+	for _, data := range colorsData {
+		var colorsArray colorDefsArray
+		err := xml.Unmarshal(data, &colorsArray)
+		if err != nil {
+			return err
+		}
+		for _, colorDef := range colorsArray.Colors {
+			// The value may be surrounded by whitespace or comments, CDATA
+			// sections are part of the character data.
+			colorDef.Color = strings.TrimSpace(colorDef.Color)
+			if i, ok := indexes[colorDef.Name]; ok {
+				colors[i] = colorDef
+			} else {
+				indexes[colorDef.Name] = len(colors)
+				colors = append(colors, colorDef)
+			}
+		}
+	}
+
+	var errs []string
+	for {
+		var remainingColors []colorDef
+		errs = nil
+		for _, colorDef := range colors {
+			color, err := ParseColor(colorDef.Color, colorDefs)
+			if err == nil {
+				colorDefs["@color/"+colorDef.Name] = color
+			} else {
+				remainingColors = append(remainingColors, colorDef)
+				errs = append(errs, fmt.Sprintf("cannot resolve color \"%s\": %s", colorDef.Name, err))
+			}
+		}
+		if len(remainingColors) == 0 || len(colors) == len(remainingColors) {
+			break
+		}
+		colors = remainingColors
+	}
+
+	if len(errs) > 0 {
+		return errors.New(strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// ParseColor parses a color given as #rgb, #argb, #rrggbb or #aarrggbb or
+// as a name defined in colorDefs. References to @color/name also resolve
+// against a plain name entry and vice versa, @android:color/name resolves
+// against the Android system colors. Theme attributes (?attr/name) cannot
+// be resolved. Unresolvable references return an error wrapping
+// ErrUnresolvedColor.
+func ParseColor(c string, colorDefs ColorDefs) (color.Color, error) {
+	if color, ok := colorDefs[c]; ok {
+		return color, nil
+	}
+
+	if name, ok := strings.CutPrefix(c, "@color/"); ok {
+		if color, ok := colorDefs[name]; ok {
+			return color, nil
+		}
+		return nil, fmt.Errorf("%w \"%s\"", ErrUnresolvedColor, c)
+	} else if name, ok := strings.CutPrefix(c, "@android:color/"); ok {
+		if color, ok := androidColors[name]; ok {
+			return color, nil
+		}
+		return nil, fmt.Errorf("%w \"%s\": \"%s\" is not an Android system color", ErrUnresolvedColor, c, name)
+	} else if strings.HasPrefix(c, "?") {
+		attr := c[strings.LastIndexAny(c, "?/")+1:]
+		return nil, fmt.Errorf("%w \"%s\": theme attribute \"%s\" cannot be resolved", ErrUnresolvedColor, c, attr)
+	} else if strings.HasPrefix(c, "@") {
+		return nil, fmt.Errorf("%w \"%s\"", ErrUnresolvedColor, c)
+	} else if color, ok := colorDefs["@color/"+c]; ok {
+		return color, nil
+	}
+
+	match := colorPattern.FindSubmatch([]byte(c))
+	if match == nil {
+		return nil, fmt.Errorf("invalid color \"%s\"", c)
+	}
+
+	spec := match[2]
+	switch len(spec) {
+	case 3, 4, 6, 8:
+	default:
+		return nil, fmt.Errorf("invalid color \"%s\": found %d hex digits, expected 3, 4, 6 or 8", c, len(spec))
+	}
+	if len(match[1]) == 0 {
+		return nil, fmt.Errorf("invalid color \"%s\": missing \"#\", did you mean \"#%s\"?", c, c)
+	}
+
+	if len(spec) == 3 {
+		r := hexToValue(spec[0])
+		g := hexToValue(spec[1])
+		b := hexToValue(spec[2])
+		return color.NRGBA{r<<4 | r, g<<4 | g, b<<4 | b, 255}, nil
+	} else if len(spec) == 4 {
+		a := hexToValue(spec[0])
+		r := hexToValue(spec[1])
+		g := hexToValue(spec[2])
+		b := hexToValue(spec[3])
+		return color.NRGBA{r<<4 | r, g<<4 | g, b<<4 | b, a<<4 | a}, nil
+	} else if len(spec) == 6 {
+		r1 := hexToValue(spec[0])
+		r2 := hexToValue(spec[1])
+		g1 := hexToValue(spec[2])
+		g2 := hexToValue(spec[3])
+		b1 := hexToValue(spec[4])
+		b2 := hexToValue(spec[5])
+		return color.NRGBA{r1<<4 | r2, g1<<4 | g2, b1<<4 | b2, 255}, nil
+	} else if len(spec) == 8 {
+		a1 := hexToValue(spec[0])
+		a2 := hexToValue(spec[1])
+		r1 := hexToValue(spec[2])
+		r2 := hexToValue(spec[3])
+		g1 := hexToValue(spec[4])
+		g2 := hexToValue(spec[5])
+		b1 := hexToValue(spec[6])
+		b2 := hexToValue(spec[7])
+		return color.NRGBA{r1<<4 | r2, g1<<4 | g2, b1<<4 | b2, a1<<4 | a2}, nil
+	}
+	return nil, fmt.Errorf("invalid color \"%s\"", c)
+}
+
+// scaleAlpha returns c with its alpha multiplied by f and whether the alpha
+// had to be clamped.
+func scaleAlpha(c color.Color, f float64) (color.Color, bool) {
+	if f == 1 {
+		return c, false
+	}
+	n := color.NRGBAModel.Convert(c).(color.NRGBA)
+	return clampedNRGBA(float64(n.R), float64(n.G), float64(n.B), float64(n.A)*f)
+}
+
+// clampedNRGBA rounds the channels to a color and reports whether any of
+// them was outside the range 0 to 255 and had to be clamped.
+func clampedNRGBA(r, g, b, a float64) (color.NRGBA, bool) {
+	clamped := false
+	channel := func(v float64) uint8 {
+		v = math.Round(v)
+		if v < 0 {
+			clamped = true
+			return 0
+		} else if v > 255 {
+			clamped = true
+			return 255
+		}
+		return uint8(v)
+	}
+	return color.NRGBA{channel(r), channel(g), channel(b), channel(a)}, clamped
+}
+
+func hexToValue(n byte) uint8 {
+	if n >= '0' && n <= '9' {
+		return n - '0'
+	} else if n >= 'a' && n <= 'f' {
+		return n - 'a' + 10
+	} else if n >= 'A' && n <= 'F' {
+		return n - 'A' + 10
+	}
+	return 0
+}
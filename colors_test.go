@@ -0,0 +1,154 @@
+package vectopng
+
+import (
+	"errors"
+	"image/color"
+	"strings"
+	"testing"
+)
+
+func TestParseColorsCircular(t *testing.T) {
+	colors := `<resources>
+	<color name="a">@color/b</color>
+	<color name="b">@color/a</color>
+	<color name="c">#ff0000</color>
+</resources>`
+	colorDefs := ColorDefs{}
+	err := ParseColors([]byte(colors), colorDefs)
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	for _, want := range []string{`cannot resolve color "a"`, `cannot resolve color "b"`} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error %q does not contain %q", err, want)
+		}
+	}
+	if strings.Contains(err.Error(), `"c"`) {
+		t.Errorf("error %q names the resolvable color c", err)
+	}
+	if got, want := colorDefs["@color/c"], (color.NRGBA{0xff, 0x00, 0x00, 0xff}); got != want {
+		t.Errorf("@color/c = %v, want %v", got, want)
+	}
+}
+
+func TestParseColorsChain(t *testing.T) {
+	// The entries reference each other in both forms and before they are
+	// defined.
+	colors := `<resources>
+	<color name="button">accent</color>
+	<color name="accent">@color/brand</color>
+	<color name="brand">#3366cc</color>
+</resources>`
+	colorDefs := ColorDefs{}
+	if err := ParseColors([]byte(colors), colorDefs); err != nil {
+		t.Fatal(err)
+	}
+	want := color.NRGBA{0x33, 0x66, 0xcc, 0xff}
+	for _, name := range []string{"@color/brand", "@color/accent", "@color/button"} {
+		if got := colorDefs[name]; got != want {
+			t.Errorf("%s = %v, want %v", name, got, want)
+		}
+	}
+}
+
+func TestParseColorsMarkup(t *testing.T) {
+	colors := `<?xml version="1.0" encoding="utf-8"?>
+<resources>
+	<!-- <color name="old">#00ff00</color> -->
+	<color name="padded">
+		#ff0000
+	</color>
+	<color name="cdata"><![CDATA[ #0000ff ]]></color>
+	<color name="commented"><!-- brand -->#ffffff</color>
+</resources>`
+	colorDefs := ColorDefs{}
+	if err := ParseColors([]byte(colors), colorDefs); err != nil {
+		t.Fatal(err)
+	}
+	want := ColorDefs{
+		"@color/padded":    color.NRGBA{0xff, 0x00, 0x00, 0xff},
+		"@color/cdata":     color.NRGBA{0x00, 0x00, 0xff, 0xff},
+		"@color/commented": color.NRGBA{0xff, 0xff, 0xff, 0xff},
+	}
+	if len(colorDefs) != len(want) {
+		t.Errorf("got %d colors, want %d", len(colorDefs), len(want))
+	}
+	for name, c := range want {
+		if got := colorDefs[name]; got != c {
+			t.Errorf("%s = %v, want %v", name, got, c)
+		}
+	}
+}
+
+func TestScaleAlpha(t *testing.T) {
+	tests := []struct {
+		name    string
+		c       color.Color
+		f       float64
+		want    color.NRGBA
+		clamped bool
+	}{
+		{"unchanged", color.NRGBA{10, 20, 30, 200}, 1, color.NRGBA{10, 20, 30, 200}, false},
+		{"halved", color.NRGBA{10, 20, 30, 200}, 0.5, color.NRGBA{10, 20, 30, 100}, false},
+		{"rounded", color.NRGBA{10, 20, 30, 255}, 0.999, color.NRGBA{10, 20, 30, 255}, false},
+		{"past 255", color.NRGBA{10, 20, 30, 200}, 1.5, color.NRGBA{10, 20, 30, 255}, true},
+		{"below 0", color.NRGBA{10, 20, 30, 200}, -0.5, color.NRGBA{10, 20, 30, 0}, true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c, clamped := scaleAlpha(test.c, test.f)
+			if got := color.NRGBAModel.Convert(c); got != test.want {
+				t.Errorf("got %v, want %v", got, test.want)
+			}
+			if clamped != test.clamped {
+				t.Errorf("got clamped %v, want %v", clamped, test.clamped)
+			}
+		})
+	}
+}
+
+func TestClampedNRGBA(t *testing.T) {
+	c, clamped := clampedNRGBA(-3, 255.4, 300, 127.5)
+	if want := (color.NRGBA{0, 255, 255, 128}); c != want {
+		t.Errorf("got %v, want %v", c, want)
+	}
+	if !clamped {
+		t.Error("channels outside the range were not reported")
+	}
+	if _, clamped := clampedNRGBA(0, 255.4, -0.4, 12); clamped {
+		t.Error("channels that round into the range were reported")
+	}
+}
+
+func TestAndroidColors(t *testing.T) {
+	tests := []struct {
+		value string
+		want  color.NRGBA
+	}{
+		{"@android:color/transparent", color.NRGBA{0x00, 0x00, 0x00, 0x00}},
+		{"@android:color/white", color.NRGBA{0xff, 0xff, 0xff, 0xff}},
+		{"@android:color/black", color.NRGBA{0x00, 0x00, 0x00, 0xff}},
+		{"@android:color/holo_blue_light", color.NRGBA{0x33, 0xb5, 0xe5, 0xff}},
+	}
+	for _, test := range tests {
+		t.Run(test.value, func(t *testing.T) {
+			got, err := ParseColor(test.value, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != test.want {
+				t.Errorf("got %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestAndroidColorErrors(t *testing.T) {
+	_, err := ParseColor("@android:color/fuchsia", nil)
+	if !errors.Is(err, ErrUnresolvedColor) {
+		t.Errorf("got error %v, want %v", err, ErrUnresolvedColor)
+	}
+	if want := `"fuchsia" is not an Android system color`; err == nil || !strings.Contains(err.Error(), want) {
+		t.Errorf("got error %v, want %q", err, want)
+	}
+}
@@ -0,0 +1,78 @@
+package vectopng
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+
+	"github.com/tdewolff/canvas"
+)
+
+// Difference describes how two images of the same size differ.
+type Difference struct {
+	// Pixels is the number of pixels whose color differs.
+	Pixels int
+	// Total is the number of pixels of each image.
+	Total int
+	// Bounds is the smallest rectangle containing all differing pixels, or
+	// empty if the images are equal.
+	Bounds image.Rectangle
+}
+
+// Percent returns the percentage of differing pixels.
+func (d Difference) Percent() float64 {
+	if d.Total == 0 {
+		return 0
+	}
+	return 100 * float64(d.Pixels) / float64(d.Total)
+}
+
+// Rasterize draws the canvas to an image of the size Save would write as
+// PNG image.
+func Rasterize(c *canvas.Canvas, opts Options) image.Image {
+	return rasterize(c, opts.scaleFactor(c), nil, &opts)
+}
+
+// Compare compares two images pixel by pixel. The images must have the same
+// size.
+func Compare(a image.Image, b image.Image) (Difference, error) {
+	ab, bb := a.Bounds(), b.Bounds()
+	if ab.Size() != bb.Size() {
+		return Difference{}, fmt.Errorf("images differ in size (%dx%d and %dx%d)", ab.Dx(), ab.Dy(), bb.Dx(), bb.Dy())
+	}
+	d := Difference{Total: ab.Dx() * ab.Dy()}
+	for y := 0; y < ab.Dy(); y++ {
+		for x := 0; x < ab.Dx(); x++ {
+			if !sameColor(a.At(ab.Min.X+x, ab.Min.Y+y), b.At(bb.Min.X+x, bb.Min.Y+y)) {
+				d.Pixels++
+				d.Bounds = d.Bounds.Union(image.Rect(x, y, x+1, y+1))
+			}
+		}
+	}
+	return d, nil
+}
+
+// HighlightDifference returns a faded copy of b with the pixels that differ
+// from a in red. The images must have the same size.
+func HighlightDifference(a image.Image, b image.Image) *image.RGBA {
+	ab, bb := a.Bounds(), b.Bounds()
+	img := image.NewRGBA(image.Rect(0, 0, bb.Dx(), bb.Dy()))
+	draw.Draw(img, img.Bounds(), image.White, image.Point{}, draw.Src)
+	draw.DrawMask(img, img.Bounds(), b, bb.Min, image.NewUniform(color.Alpha{0x40}), image.Point{}, draw.Over)
+	highlight := color.RGBA{0xff, 0x00, 0x00, 0xff}
+	for y := 0; y < bb.Dy(); y++ {
+		for x := 0; x < bb.Dx(); x++ {
+			if !sameColor(a.At(ab.Min.X+x, ab.Min.Y+y), b.At(bb.Min.X+x, bb.Min.Y+y)) {
+				img.SetRGBA(x, y, highlight)
+			}
+		}
+	}
+	return img
+}
+
+// sameColor compares the colors as non-premultiplied 8-bit values, so that
+// a rendered image equals the same image read from a PNG file.
+func sameColor(a color.Color, b color.Color) bool {
+	return color.NRGBAModel.Convert(a) == color.NRGBAModel.Convert(b)
+}
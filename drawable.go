@@ -0,0 +1,67 @@
+package vectopng
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/tdewolff/canvas"
+)
+
+// drawDrawable draws the vector drawable that a path references with
+// android:drawable instead of path data. The drawable is read from the
+// DrawableDir option and its viewport is scaled to the viewport of the
+// referencing vector. Its own tint is not applied, the tint of the
+// referencing vector applies to all of its paths, while its android:alpha
+// is multiplied into that of the referencing vector. References are followed
+// recursively, a cycle is an error.
+func (r *renderer) drawDrawable(pathElem *vectorPath, i int) error {
+	name, ok := strings.CutPrefix(pathElem.Drawable, "@drawable/")
+	if !ok || name == "" {
+		return fmt.Errorf("invalid drawable reference \"%s\" of path %d", pathElem.Drawable, i)
+	}
+	if r.opts.DrawableDir == "" {
+		return fmt.Errorf("cannot resolve drawable reference \"%s\" of path %d without a drawable directory", pathElem.Drawable, i)
+	}
+	if slices.Contains(r.drawables, name) {
+		return fmt.Errorf("drawable reference cycle %s", strings.Join(append(r.drawables, name), " -> "))
+	}
+	xmlData, err := os.ReadFile(filepath.Join(r.opts.DrawableDir, name+".xml"))
+	if err != nil {
+		return fmt.Errorf("cannot read drawable \"%s\" of path %d: %w", name, i, err)
+	}
+	opts := *r.opts
+	if opts.Warn != nil {
+		opts.Warn = func(warning string) {
+			r.opts.Warn(fmt.Sprintf("drawable \"%s\": %s", name, warning))
+		}
+	}
+	vec, err := parseVector(xmlData, opts)
+	if err != nil {
+		return fmt.Errorf("drawable \"%s\" of path %d: %w", name, i, err)
+	}
+
+	m := canvas.Identity.Scale(r.vec.ViewportWidth/vec.ViewportWidth, r.vec.ViewportHeight/vec.ViewportHeight)
+	if r.ctx != nil {
+		view := r.ctx.View()
+		r.ctx.SetView(view.Mul(m))
+		defer r.ctx.SetView(view)
+	} else {
+		transform := r.transform
+		r.transform = transform.Mul(m)
+		defer func() { r.transform = transform }()
+	}
+	parent, pathIndex, groups, clip, alpha := r.vec, r.pathIndex, r.groups, r.clip, r.alpha
+	r.vec, r.pathIndex, r.groups, r.alpha = vec, 0, canvas.Identity, alpha*vec.Alpha
+	r.drawables = append(r.drawables, name)
+	defer func() {
+		r.vec, r.pathIndex, r.groups, r.clip, r.alpha = parent, pathIndex, groups, clip, alpha
+		r.drawables = r.drawables[:len(r.drawables)-1]
+	}()
+	if err := r.drawNodes(vec.Children); err != nil {
+		return fmt.Errorf("drawable \"%s\": %w", name, err)
+	}
+	return nil
+}
@@ -0,0 +1,242 @@
+package vectopng
+
+import (
+	"cmp"
+	"math"
+	"slices"
+
+	"github.com/tdewolff/canvas"
+)
+
+// evenOddPath returns a path that fills the same area with the nonZero rule
+// as the given path with the evenOdd rule. If the subpaths neither intersect
+// themselves nor each other, filled subpaths are turned counter clockwise
+// and holes clockwise, so the windings of nested subpaths cancel out and
+// the curves are kept. Otherwise the flattened outline of the evenOdd area
+// is computed, such as the pentagon around the centre of a pentagram.
+func evenOddPath(path *canvas.Path) *canvas.Path {
+	edges, split := splitEdges(path.Flatten(flattenTolerance(path)))
+	if !split {
+		subpaths := path.Split()
+		filling := path.Filling(canvas.EvenOdd)
+		p := &canvas.Path{}
+		for i, subpath := range subpaths {
+			if subpath.CCW() != filling[i] {
+				subpath = subpath.Reverse()
+			}
+			p = p.Append(subpath)
+		}
+		return p
+	}
+	return linkEdges(orientEdges(edges, flattenTolerance(path)))
+}
+
+// edge is a line segment of a flattened path.
+type edge struct {
+	a, b canvas.Point
+}
+
+// splitEdges returns the edges of the flattened path split at all points
+// where they cross or touch other edges, so that edges only meet at their
+// ends, and whether any edge had to be split. Coinciding edges are removed
+// in pairs, since crossing both of them does not change the evenOdd parity.
+func splitEdges(path *canvas.Path) ([]edge, bool) {
+	var edges []edge
+	for _, subpath := range path.Split() {
+		points := polygon(subpath)
+		for i, a := range points {
+			if b := points[(i+1)%len(points)]; a != b {
+				edges = append(edges, edge{a, b})
+			}
+		}
+	}
+
+	// The splits of each edge are given by their position t along it.
+	type cut struct {
+		t float64
+		p canvas.Point
+	}
+	cuts := make([][]cut, len(edges))
+	addCut := func(i int, t float64, p canvas.Point) {
+		if t > 0 && t < 1 && p != edges[i].a && p != edges[i].b {
+			cuts[i] = append(cuts[i], cut{t, p})
+		}
+	}
+	// project returns the position of p along the edge if p lies on it.
+	project := func(e edge, p canvas.Point) (float64, bool) {
+		r := e.b.Sub(e.a)
+		t := p.Sub(e.a).Dot(r) / r.Dot(r)
+		d := e.a.Interpolate(e.b, t).Sub(p).Length()
+		return t, d < canvas.Epsilon*math.Max(1, r.Length())
+	}
+
+	// The edges are swept in order of their left end to only compare those
+	// whose horizontal extents overlap.
+	order := make([]int, len(edges))
+	for i := range order {
+		order[i] = i
+	}
+	minX := func(e edge) float64 { return math.Min(e.a.X, e.b.X) }
+	maxX := func(e edge) float64 { return math.Max(e.a.X, e.b.X) }
+	slices.SortFunc(order, func(i, j int) int {
+		return cmp.Compare(minX(edges[i]), minX(edges[j]))
+	})
+	for k, i := range order {
+		e := edges[i]
+		for _, j := range order[k+1:] {
+			f := edges[j]
+			if minX(f) > maxX(e) {
+				break
+			}
+			if math.Min(f.a.Y, f.b.Y) > math.Max(e.a.Y, e.b.Y) || math.Max(f.a.Y, f.b.Y) < math.Min(e.a.Y, e.b.Y) {
+				continue
+			}
+			// The ends of one edge that lie on the other split it, which also
+			// covers collinear overlaps.
+			touched := false
+			for _, p := range []canvas.Point{f.a, f.b} {
+				if t, ok := project(e, p); ok && t > 0 && t < 1 {
+					addCut(i, t, p)
+					touched = true
+				}
+			}
+			for _, p := range []canvas.Point{e.a, e.b} {
+				if t, ok := project(f, p); ok && t > 0 && t < 1 {
+					addCut(j, t, p)
+					touched = true
+				}
+			}
+			if touched {
+				continue
+			}
+			r, s := e.b.Sub(e.a), f.b.Sub(f.a)
+			denom := r.PerpDot(s)
+			if math.Abs(denom) < canvas.Epsilon*r.Length()*s.Length() {
+				continue
+			}
+			d := f.a.Sub(e.a)
+			t, u := d.PerpDot(s)/denom, d.PerpDot(r)/denom
+			if t > 0 && t < 1 && u > 0 && u < 1 {
+				// The crossing is computed once, so that the pieces of both
+				// edges end at exactly the same point.
+				p := e.a.Interpolate(e.b, t)
+				addCut(i, t, p)
+				addCut(j, u, p)
+			}
+		}
+	}
+
+	split := false
+	counts := make(map[edge]int)
+	var pieces []edge
+	for i, e := range edges {
+		slices.SortFunc(cuts[i], func(x, y cut) int { return cmp.Compare(x.t, y.t) })
+		points := []canvas.Point{e.a}
+		for _, c := range cuts[i] {
+			if c.p != points[len(points)-1] {
+				points = append(points, c.p)
+			}
+		}
+		points = append(points, e.b)
+		split = split || len(points) > 2
+		for k := 1; k < len(points); k++ {
+			piece := edge{points[k-1], points[k]}
+			pieces = append(pieces, piece)
+			counts[piece.unordered()]++
+		}
+	}
+	var result []edge
+	for _, piece := range pieces {
+		key := piece.unordered()
+		if counts[key] > 1 {
+			split = true
+		}
+		// One of an odd number of coinciding pieces remains.
+		if counts[key]%2 == 1 {
+			result = append(result, piece)
+			counts[key] = 0
+		} else if counts[key] > 0 {
+			counts[key] = 0
+		}
+	}
+	return result, split
+}
+
+// unordered returns the edge with its ends in a fixed order, so that edges
+// running in opposite directions compare equal.
+func (e edge) unordered() edge {
+	if e.b.X < e.a.X || e.b.X == e.a.X && e.b.Y < e.a.Y {
+		return edge{e.b, e.a}
+	}
+	return e
+}
+
+// orientEdges turns the edges so that the evenOdd area lies on their left.
+// Since the edges only meet at their ends, exactly one side of each edge is
+// inside, which is tested just next to its middle.
+func orientEdges(edges []edge, tolerance float64) []edge {
+	inside := func(q canvas.Point) bool {
+		in := false
+		for _, e := range edges {
+			if (e.a.Y > q.Y) != (e.b.Y > q.Y) {
+				x := e.a.X + (q.Y-e.a.Y)*(e.b.X-e.a.X)/(e.b.Y-e.a.Y)
+				if q.X < x {
+					in = !in
+				}
+			}
+		}
+		return in
+	}
+	// The test point is far closer to the edge than the flattening is
+	// exact, but far enough to be clearly on one side of it.
+	offset := tolerance * 1e-3
+	oriented := make([]edge, len(edges))
+	for i, e := range edges {
+		d := e.b.Sub(e.a)
+		left := e.a.Interpolate(e.b, 0.5).Add(d.Rot90CCW().Norm(math.Min(offset, d.Length()/4)))
+		if inside(left) {
+			oriented[i] = e
+		} else {
+			oriented[i] = edge{e.b, e.a}
+		}
+	}
+	return oriented
+}
+
+// linkEdges joins the oriented edges into closed subpaths. Every point where
+// edges meet has as many edges arriving as leaving, so following the edges
+// returns to the start.
+func linkEdges(edges []edge) *canvas.Path {
+	outgoing := make(map[canvas.Point][]int)
+	for i, e := range edges {
+		outgoing[e.a] = append(outgoing[e.a], i)
+	}
+	used := make([]bool, len(edges))
+	next := func(p canvas.Point) (int, bool) {
+		for _, i := range outgoing[p] {
+			if !used[i] {
+				return i, true
+			}
+		}
+		return 0, false
+	}
+
+	p := &canvas.Path{}
+	for i := range edges {
+		if used[i] {
+			continue
+		}
+		start := edges[i].a
+		p.MoveTo(start.X, start.Y)
+		for j, ok := i, true; ok; j, ok = next(edges[j].b) {
+			used[j] = true
+			if end := edges[j].b; end != start {
+				p.LineTo(end.X, end.Y)
+			} else {
+				break
+			}
+		}
+		p.Close()
+	}
+	return p
+}
@@ -0,0 +1,154 @@
+package vectopng
+
+import (
+	"image"
+	"testing"
+)
+
+func TestEvenOddFill(t *testing.T) {
+	const pentagram = "M50,5 L76.5,86.5 L7.2,36.1 L92.8,36.1 L23.5,86.5 Z"
+	tests := []struct {
+		name     string
+		pathData string
+		stroke   string
+		filled   []image.Point
+		holes    []image.Point
+		stroked  []image.Point
+	}{
+		{
+			name:     "pentagram",
+			pathData: pentagram,
+			filled:   []image.Point{{50, 20}, {20, 40}, {80, 40}, {30, 75}, {70, 75}},
+			holes:    []image.Point{{50, 50}, {45, 45}, {55, 55}, {10, 90}},
+		},
+		{
+			name:     "overlapping squares",
+			pathData: "M10,10h50v50h-50z M40,40h50v50h-50z",
+			filled:   []image.Point{{20, 20}, {30, 50}, {70, 70}, {85, 85}},
+			holes:    []image.Point{{50, 50}, {45, 55}, {80, 20}, {20, 80}},
+		},
+		{
+			name:     "overlapping squares in opposite directions",
+			pathData: "M10,10h50v50h-50z M40,40v50h50v-50z",
+			filled:   []image.Point{{20, 20}, {70, 70}},
+			holes:    []image.Point{{50, 50}},
+		},
+		{
+			name:     "nested squares",
+			pathData: "M10,10h80v80h-80z M30,30h40v40h-40z",
+			filled:   []image.Point{{20, 20}, {80, 80}},
+			holes:    []image.Point{{50, 50}},
+		},
+		{
+			name:     "squares sharing an edge",
+			pathData: "M10,10h40v40h-40z M50,10h40v40h-40z",
+			filled:   []image.Point{{30, 30}, {70, 30}, {50, 30}},
+			holes:    []image.Point{{50, 70}},
+		},
+		{
+			name:     "figure eight",
+			pathData: "M10,10 L90,90 L90,10 L10,90 Z",
+			filled:   []image.Point{{20, 50}, {80, 50}},
+			holes:    []image.Point{{50, 20}, {50, 80}},
+		},
+		{
+			name:     "stroked nested squares",
+			pathData: "M10,10h80v80h-80z M30,30h40v40h-40z",
+			stroke:   "#f00",
+			filled:   []image.Point{{20, 20}, {20, 50}, {80, 80}},
+			holes:    []image.Point{{50, 50}, {35, 35}, {65, 65}},
+			stroked:  []image.Point{{10, 50}, {50, 89}, {30, 50}, {50, 69}},
+		},
+		{
+			name:     "stroked pentagram",
+			pathData: pentagram,
+			stroke:   "#f00",
+			filled:   []image.Point{{50, 20}, {20, 40}, {80, 40}, {30, 75}, {70, 75}},
+			holes:    []image.Point{{50, 50}, {45, 45}, {55, 55}, {10, 90}},
+			stroked:  []image.Point{{54, 20}, {20, 36}, {80, 36}, {50, 36}},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			attrs := `android:fillType="evenOdd" android:fillColor="#000"`
+			if test.stroke != "" {
+				attrs += ` android:strokeColor="` + test.stroke + `" android:strokeWidth="2"`
+			}
+			img := render(t, testVector(`<path `+attrs+` android:pathData="`+test.pathData+`"/>`), Options{})
+			for _, p := range test.filled {
+				// The edges of the outline filled in place of the evenOdd
+				// area must not be stroked.
+				if r, _, _, a := img.At(p.X, p.Y).RGBA(); a>>8 != 0xff || r != 0 {
+					t.Errorf("pixel at %v is %v, want opaque black", p, img.At(p.X, p.Y))
+				}
+			}
+			for _, p := range test.holes {
+				if a := alphaAt(img, p.X, p.Y); a != 0 {
+					t.Errorf("alpha at %v is %d, want 0", p, a)
+				}
+			}
+			for _, p := range test.stroked {
+				if r, _, _, a := img.At(p.X, p.Y).RGBA(); a>>8 != 0xff || r>>8 < 0xc0 {
+					t.Errorf("pixel at %v is %v, want the stroke", p, img.At(p.X, p.Y))
+				}
+			}
+		})
+	}
+}
+
+func TestEvenOddPathKeepsCurves(t *testing.T) {
+	path, err := (&Options{}).parsePath("M10,50 C10,0 90,0 90,50 C90,100 10,100 10,50z M30,50 C30,40 70,40 70,50 C70,60 30,60 30,50z")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := evenOddPath(path); got.Len() != path.Len() {
+		t.Errorf("path without intersections was flattened to %d segments, want %d", got.Len(), path.Len())
+	}
+}
+
+func TestEvenOddClip(t *testing.T) {
+	tests := []struct {
+		name     string
+		fillType string
+		pathData string
+		filled   []image.Point
+		holes    []image.Point
+	}{
+		{
+			name:     "self-intersecting evenOdd",
+			fillType: "evenOdd",
+			pathData: "M50,5 L76.5,86.5 L7.2,36.1 L92.8,36.1 L23.5,86.5 Z",
+			filled:   []image.Point{{50, 20}, {20, 40}, {70, 75}},
+			holes:    []image.Point{{50, 50}, {10, 90}},
+		},
+		{
+			name:     "self-intersecting nonZero",
+			pathData: "M50,5 L76.5,86.5 L7.2,36.1 L92.8,36.1 L23.5,86.5 Z",
+			filled:   []image.Point{{50, 20}, {50, 50}},
+			holes:    []image.Point{{10, 90}},
+		},
+		{
+			name:     "overlapping evenOdd",
+			fillType: "evenOdd",
+			pathData: "M10,10h50v50h-50z M40,40h50v50h-50z",
+			filled:   []image.Point{{20, 20}, {70, 70}},
+			holes:    []image.Point{{50, 50}, {80, 20}},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			img := render(t, testVector(`<clip-path android:fillType="`+test.fillType+`" android:pathData="`+test.pathData+`"/>
+<path android:fillColor="#000" android:pathData="M0,0h100v100h-100z"/>`), Options{})
+			for _, p := range test.filled {
+				if a := alphaAt(img, p.X, p.Y); a != 0xff {
+					t.Errorf("alpha at %v is %d, want 255", p, a)
+				}
+			}
+			for _, p := range test.holes {
+				if a := alphaAt(img, p.X, p.Y); a != 0 {
+					t.Errorf("alpha at %v is %d, want 0", p, a)
+				}
+			}
+		})
+	}
+}
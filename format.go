@@ -0,0 +1,399 @@
+package vectopng
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/HugoSmits86/nativewebp"
+	"github.com/tdewolff/canvas"
+	"github.com/tdewolff/canvas/renderers/rasterizer"
+	"github.com/tdewolff/canvas/renderers/svg"
+)
+
+// Format is an output image format.
+type Format string
+
+const (
+	FormatPNG  Format = "png"
+	FormatJPEG Format = "jpeg"
+	FormatWebP Format = "webp"
+	FormatSVG  Format = "svg"
+	FormatICO  Format = "ico"
+	FormatICNS Format = "icns"
+)
+
+var svgSizePattern = regexp.MustCompile(`width="[^"]*mm" height="[^"]*mm"`)
+
+// ParseFormat parses a format name such as "png", "jpeg", "webp", "svg",
+// "ico" or "icns".
+func ParseFormat(s string) (Format, error) {
+	switch f := Format(strings.ToLower(s)); f {
+	case FormatPNG, FormatJPEG, FormatWebP, FormatSVG, FormatICO, FormatICNS:
+		return f, nil
+	case "jpg":
+		return FormatJPEG, nil
+	}
+	return "", fmt.Errorf("unknown format \"%s\"", s)
+}
+
+// FormatFromPath returns the format matching the file extension of p and
+// FormatPNG if the extension is unknown.
+func FormatFromPath(p string) Format {
+	if f, err := ParseFormat(strings.TrimPrefix(filepath.Ext(p), ".")); err == nil {
+		return f
+	}
+	return FormatPNG
+}
+
+// saveCanvas writes the canvas in the given format to the file p of fsys.
+// The image is encoded before the file is created, so that an encoding
+// error leaves no partial file behind.
+func saveCanvas(fsys WriteFS, c *canvas.Canvas, p string, format Format, scaleFactor float64, opts *Options) error {
+	var buf bytes.Buffer
+	if err := writeImage(&buf, c, format, scaleFactor, opts); err != nil {
+		return fmt.Errorf("cannot save image to \"%s\": %w", p, err)
+	}
+	f, err := fsys.Create(p)
+	if err == nil {
+		_, err = f.Write(buf.Bytes())
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("cannot save image to \"%s\": %w", p, err)
+	}
+	return nil
+}
+
+// writeImage writes the canvas in the given format to w.
+func writeImage(w io.Writer, c *canvas.Canvas, format Format, scaleFactor float64, opts *Options) error {
+	switch format {
+	case FormatSVG:
+		return writeSVG(w, c, scaleFactor)
+	case FormatICO:
+		return writeICO(w, c, iconSizes(format, opts), opts)
+	case FormatICNS:
+		return writeICNS(w, c, opts)
+	case FormatJPEG:
+		// JPEG has no alpha channel, so transparent areas need a background.
+		// Any explicit background has already been drawn by renderVector.
+		var background color.Color
+		if opts.Flatten != nil {
+			background = opaque(opts.Flatten)
+		} else if opts.Background == nil {
+			background = color.White
+		}
+		quality := opts.Quality
+		if quality <= 0 {
+			quality = jpeg.DefaultQuality
+		}
+		return jpeg.Encode(w, rasterize(c, scaleFactor, background, opts), &jpeg.Options{Quality: quality})
+	}
+	var background color.Color
+	if opts.Flatten != nil {
+		// The PNG encoder writes opaque images without alpha channel.
+		background = opaque(opts.Flatten)
+	}
+	if format == FormatWebP {
+		return nativewebp.Encode(w, rasterize(c, scaleFactor, background, opts), &nativewebp.Options{CompressionLevel: webpCompression(opts.Quality)})
+	}
+	return encodePNG(w, rasterize(c, scaleFactor, background, opts), opts)
+}
+
+// webpCompression returns the compression level of lossless WebP images for
+// the quality, which like for cwebp -lossless trades the encoding time for
+// a smaller file instead of losing detail.
+func webpCompression(quality int) nativewebp.CompressionLevel {
+	if quality <= 0 {
+		return nativewebp.DefaultCompression
+	}
+	return nativewebp.CompressionLevel(min(quality, 100) * int(nativewebp.BestCompression) / 100)
+}
+
+// opaque returns the color c with full alpha.
+func opaque(c color.Color) color.Color {
+	nrgba := color.NRGBAModel.Convert(c).(color.NRGBA)
+	nrgba.A = 0xff
+	return nrgba
+}
+
+// MIMEType returns the media type of the format, such as "image/png".
+func (f Format) MIMEType() string {
+	switch f {
+	case FormatJPEG:
+		return "image/jpeg"
+	case FormatWebP:
+		return "image/webp"
+	case FormatSVG:
+		return "image/svg+xml"
+	case FormatICO:
+		return "image/x-icon"
+	case FormatICNS:
+		return "image/icns"
+	}
+	return "image/png"
+}
+
+// iconSizes returns the pixel sizes of the images in ICO and ICNS files.
+func iconSizes(format Format, opts *Options) []int {
+	switch format {
+	case FormatICO:
+		if len(opts.ICOSizes) > 0 {
+			return opts.ICOSizes
+		}
+		return DefaultICOSizes
+	case FormatICNS:
+		sizes := make([]int, len(icnsTypes))
+		for i, t := range icnsTypes {
+			sizes[i] = t.Size
+		}
+		return sizes
+	}
+	return nil
+}
+
+// pngEncoder encodes all PNG images with a fixed compression level. The
+// encoder writes no time or other varying chunks, so the same image always
+// results in the same bytes.
+var pngEncoder = png.Encoder{CompressionLevel: png.DefaultCompression}
+
+// encodePNG writes the image as PNG. The chunks declaring the color space
+// and, if opts.DPI is greater than zero, the density follow opts. The
+// density is stored in a pHYs chunk, which does not change the pixel size.
+func encodePNG(w io.Writer, img image.Image, opts *Options) error {
+	var chunks [][]byte
+	if opts.ColorProfile != nil {
+		var profile bytes.Buffer
+		zw := zlib.NewWriter(&profile)
+		if _, err := zw.Write(opts.ColorProfile); err != nil {
+			return err
+		}
+		if err := zw.Close(); err != nil {
+			return err
+		}
+		// The profile name is followed by a null separator and the
+		// compression method 0 (deflate).
+		chunks = append(chunks, pngChunk("iCCP", append([]byte("ICC profile\x00\x00"), profile.Bytes()...)))
+	} else if !opts.NoColorProfile {
+		chunks = append(chunks, pngChunk("sRGB", []byte{0})) // perceptual intent
+	}
+	if opts.DPI > 0 {
+		phys := make([]byte, 9)
+		ppm := uint32(opts.DPI/0.0254 + 0.5)
+		binary.BigEndian.PutUint32(phys, ppm)
+		binary.BigEndian.PutUint32(phys[4:], ppm)
+		phys[8] = 1 // unit is the meter
+		chunks = append(chunks, pngChunk("pHYs", phys))
+	}
+	if len(chunks) == 0 {
+		return pngEncoder.Encode(w, img)
+	}
+	var buf bytes.Buffer
+	if err := pngEncoder.Encode(&buf, img); err != nil {
+		return err
+	}
+
+	// The chunks must precede the image data, so they are inserted right
+	// after the signature and the IHDR chunk, which always has 13 bytes.
+	const ihdrEnd = 8 + 12 + 13
+	data := buf.Bytes()
+	parts := append([][]byte{data[:ihdrEnd]}, chunks...)
+	for _, part := range append(parts, data[ihdrEnd:]) {
+		if _, err := w.Write(part); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// pngChunk returns a PNG chunk with its length and checksum.
+func pngChunk(chunkType string, data []byte) []byte {
+	chunk := make([]byte, 12+len(data))
+	binary.BigEndian.PutUint32(chunk, uint32(len(data)))
+	copy(chunk[4:], chunkType)
+	copy(chunk[8:], data)
+	binary.BigEndian.PutUint32(chunk[8+len(data):], crc32.ChecksumIEEE(chunk[4:8+len(data)]))
+	return chunk
+}
+
+// rasterize draws the canvas to an image and composites it over the
+// background color unless it is nil. Pixel snapping, anti-aliasing, the
+// scale snapping and the blending color space follow opts.
+func rasterize(c *canvas.Canvas, scaleFactor float64, background color.Color, opts *Options) image.Image {
+	resolution := canvas.DPMM(scaleFactor)
+	img := image.NewRGBA(image.Rect(0, 0, int(c.W*scaleFactor+0.5), int(c.H*scaleFactor+0.5)))
+	colorSpace := canvas.DefaultColorSpace
+	if opts.LinearBlend {
+		colorSpace = canvas.SRGBColorSpace{}
+	}
+	ras := rasterizer.FromImage(img, resolution, colorSpace)
+	renderer := &pixelRenderer{
+		Rasterizer:  ras,
+		img:         img,
+		resolution:  resolution,
+		colorSpace:  colorSpace,
+		noAntialias: opts.NoAntialias,
+		pixelSnap:   opts.PixelSnap,
+		tiles:       opts.PathCache,
+	}
+	if opts.SnapScale {
+		// The canvas is scaled about its bottom left corner in y-up
+		// coordinates, so that its top right corner meets the image corner.
+		size := img.Bounds().Size()
+		stretch := canvas.Identity.Scale(float64(size.X)/(c.W*scaleFactor), float64(size.Y)/(c.H*scaleFactor))
+		renderer.stretch = &stretch
+	}
+	c.RenderTo(renderer)
+	ras.Close()
+	if background == nil {
+		return img
+	}
+
+	bounds := img.Bounds()
+	composed := image.NewRGBA(bounds)
+	draw.Draw(composed, bounds, image.NewUniform(background), image.Point{}, draw.Src)
+	draw.Draw(composed, bounds, img, bounds.Min, draw.Over)
+	return composed
+}
+
+// writeSVG writes the canvas as SVG. The SVG renderer declares the size in
+// mm, so it is replaced by the pixel size of the equivalent PNG while the
+// viewBox keeps the canvas coordinates. The paths stay editable: colors keep
+// their alpha, and the even-odd fill rule of the style and
+// canvas.LinearGradient and canvas.RadialGradient paints are written as
+// fill-rule attributes and gradient definitions instead of being rasterized.
+// Paths within a clip-path are written as their clipped nonZero outline.
+// Repeated and mirrored gradients are written with the spreadMethod repeat
+// and reflect, but SVG has no sweep gradients, so a canvas painted with one
+// fails.
+func writeSVG(w io.Writer, c *canvas.Canvas, scaleFactor float64) error {
+	var buf bytes.Buffer
+	renderer := newSVGRenderer(&buf, c.W, c.H)
+	c.RenderTo(renderer)
+	if err := renderer.Close(); err != nil {
+		return err
+	}
+	if renderer.err != nil {
+		return renderer.err
+	}
+
+	width := int(c.W*scaleFactor + 0.5)
+	height := int(c.H*scaleFactor + 0.5)
+	size := fmt.Sprintf(`width="%d" height="%d"`, width, height)
+	data := svgSizePattern.ReplaceAll(renderer.spread(buf.Bytes()), []byte(size))
+	_, err := w.Write(data)
+	return err
+}
+
+// svgRenderer is the SVG renderer of canvas, which writes the definition of
+// a gradient only for canvas.LinearGradient and canvas.RadialGradient and
+// refers to an empty one for a viewportGradient. It renders repeated and
+// mirrored gradients as their linear or radial gradient instead, noting
+// their tile mode, and keeps the first path painted with a sweep gradient
+// as an error.
+type svgRenderer struct {
+	*svg.SVG
+	w io.Writer
+	// filters is the number of tint filters written.
+	filters int
+	// ids numbers the gradients in the order the SVG renderer defines them,
+	// which gives them the ids p1, p2 and so on.
+	ids   map[canvas.Gradient]int
+	modes map[int]tileMode
+	err   error
+}
+
+func newSVGRenderer(w io.Writer, width, height float64) *svgRenderer {
+	return &svgRenderer{
+		SVG:   svg.New(w, width, height, nil),
+		w:     w,
+		ids:   make(map[canvas.Gradient]int),
+		modes: make(map[int]tileMode),
+	}
+}
+
+// RenderPath renders the path unless its paint cannot be written as SVG.
+// The paths of a tinted vector are written as a group with the tint as
+// filter.
+func (r *svgRenderer) RenderPath(path *canvas.Path, style canvas.Style, m canvas.Matrix) {
+	if filter, ok := style.Fill.Gradient.(*tintFilter); ok {
+		r.renderFilter(filter, path, m)
+		return
+	}
+	var paints []*canvas.Paint
+	if style.HasFill() && style.Fill.IsGradient() {
+		paints = append(paints, &style.Fill)
+	}
+	if style.HasStroke() && style.Stroke.IsGradient() {
+		paints = append(paints, &style.Stroke)
+	}
+	for _, paint := range paints {
+		mode := tileClamp
+		if g, ok := paint.Gradient.(*viewportGradient); ok {
+			if g.svg == nil {
+				if r.err == nil {
+					r.err = fmt.Errorf("SVG has no sweep gradients")
+				}
+				return
+			}
+			paint.Gradient, mode = g.svg, g.mode
+		}
+		id, ok := r.ids[paint.Gradient]
+		if !ok {
+			id = len(r.ids) + 1
+			r.ids[paint.Gradient] = id
+		}
+		if mode != tileClamp {
+			r.modes[id] = mode
+		}
+	}
+	r.SVG.RenderPath(path, style, m)
+}
+
+// spread adds the spreadMethod attribute to the definitions of the repeated
+// and mirrored gradients.
+func (r *svgRenderer) spread(data []byte) []byte {
+	for id, mode := range r.modes {
+		method := "repeat"
+		if mode == tileMirror {
+			method = "reflect"
+		}
+		for _, element := range []string{"<linearGradient", "<radialGradient"} {
+			def := fmt.Sprintf(`%s id="p%d"`, element, id)
+			data = bytes.Replace(data, []byte(def), []byte(fmt.Sprintf(`%s spreadMethod="%s"`, def, method)), 1)
+		}
+	}
+	return data
+}
+
+// renderFilter starts the group of a tinted vector or ends it. The group is
+// clipped to the area, since the tint of some modes fills the whole filter
+// region.
+func (r *svgRenderer) renderFilter(filter *tintFilter, area *canvas.Path, m canvas.Matrix) {
+	if filter.end {
+		fmt.Fprintf(r.w, `</g>`)
+		return
+	}
+	r.filters++
+	width, height := r.Size()
+	area = area.Transform(canvas.Identity.ReflectYAbout(height / 2).Mul(m))
+	fmt.Fprintf(r.w, `<defs>%s<clipPath id="tintclip%d"><path d="%s"/></clipPath></defs>`, filter.svg(fmt.Sprintf("tint%d", r.filters), width, height), r.filters, area.ToSVG())
+	fmt.Fprintf(r.w, `<g filter="url(#tint%d)" clip-path="url(#tintclip%d)"`, r.filters, r.filters)
+	if filter.alpha < 1 {
+		fmt.Fprintf(r.w, ` opacity="%g"`, filter.alpha)
+	}
+	fmt.Fprintf(r.w, `>`)
+}
@@ -0,0 +1,295 @@
+package vectopng
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"strings"
+	"testing"
+
+	"golang.org/x/image/webp"
+)
+
+func TestSVGFillRule(t *testing.T) {
+	tests := []struct {
+		name     string
+		elements string
+		want     []string
+		notWant  []string
+	}{
+		{
+			name:     "evenOdd",
+			elements: `<path android:fillType="evenOdd" android:fillColor="#ff0000" android:pathData="M10,10h80v80h-80z M30,30h40v40h-40z"/>`,
+			want:     []string{`fill-rule="evenodd"`, `d="M10 10H90V90H10zM30 30H70V70H30z"`},
+		},
+		{
+			name:     "evenOdd with stroke",
+			elements: `<path android:fillType="evenOdd" android:fillColor="#ff0000" android:strokeColor="#0000ff" android:strokeWidth="2" android:pathData="M50,5 L76.5,86.5 L7.2,36.1 L92.8,36.1 L23.5,86.5 Z"/>`,
+			want:     []string{`fill-rule:evenodd`, `d="M50 5L76.5 86.5L7.2 36.1H92.8L23.5 86.5z"`},
+		},
+		{
+			name:     "nonZero",
+			elements: `<path android:fillColor="#ff0000" android:pathData="M10,10h80v80h-80z M30,30h40v40h-40z"/>`,
+			notWant:  []string{"evenodd"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c, err := Convert([]byte(testVector(test.elements)), Options{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			var buf bytes.Buffer
+			if _, err := Write(&buf, c, FormatSVG, Options{}); err != nil {
+				t.Fatal(err)
+			}
+			svg := buf.String()
+			for _, s := range test.want {
+				if !strings.Contains(svg, s) {
+					t.Errorf("SVG lacks %s:\n%s", s, svg)
+				}
+			}
+			for _, s := range test.notWant {
+				if strings.Contains(svg, s) {
+					t.Errorf("SVG contains %s:\n%s", s, svg)
+				}
+			}
+		})
+	}
+}
+
+func TestSVGGradientErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		gradient string
+		want     string
+	}{
+		{
+			name:     "sweep",
+			gradient: `<gradient android:type="sweep" android:centerX="50" android:centerY="50" android:startColor="#ff0000" android:endColor="#0000ff"/>`,
+			want:     "SVG has no sweep gradients",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c, err := Convert([]byte(testGradientVector(test.gradient)), Options{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			var buf bytes.Buffer
+			_, err = Write(&buf, c, FormatSVG, Options{})
+			if err == nil || !strings.Contains(err.Error(), test.want) {
+				t.Fatalf("got error %v, want %s", err, test.want)
+			}
+		})
+	}
+}
+
+func TestSVGSpreadMethod(t *testing.T) {
+	tests := []struct {
+		name     string
+		gradient string
+		want     string
+	}{
+		{
+			name:     "linear repeat",
+			gradient: `<gradient android:startX="0" android:endX="25" android:tileMode="repeat" android:startColor="#ff0000" android:endColor="#0000ff"/>`,
+			want:     `<linearGradient id="p1" spreadMethod="repeat" gradientUnits="userSpaceOnUse" x1="0" y1="0" x2="25" y2="0">`,
+		},
+		{
+			name:     "radial mirror",
+			gradient: `<gradient android:type="radial" android:centerX="50" android:centerY="50" android:gradientRadius="10" android:tileMode="mirror" android:startColor="#ff0000" android:endColor="#0000ff"/>`,
+			want:     `<radialGradient id="p1" spreadMethod="reflect" gradientUnits="userSpaceOnUse" fx="50" fy="50" fr="0" cx="50" cy="50" r="10">`,
+		},
+		{
+			name:     "linear clamp",
+			gradient: `<gradient android:startX="0" android:endX="25" android:tileMode="clamp" android:startColor="#ff0000" android:endColor="#0000ff"/>`,
+			want:     `<linearGradient id="p1" gradientUnits="userSpaceOnUse" x1="0" y1="0" x2="25" y2="0">`,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c, err := Convert([]byte(testGradientVector(test.gradient)), Options{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			var buf bytes.Buffer
+			if _, err := Write(&buf, c, FormatSVG, Options{}); err != nil {
+				t.Fatal(err)
+			}
+			if svg := buf.String(); !strings.Contains(svg, test.want) || !strings.Contains(svg, `fill="url(#p1)"`) {
+				t.Errorf("SVG lacks %s:\n%s", test.want, svg)
+			}
+		})
+	}
+}
+
+// testGradientVector returns a vector drawable with a square filled with the
+// gradient.
+func testGradientVector(gradient string) string {
+	return `<vector xmlns:android="http://schemas.android.com/apk/res/android" xmlns:aapt="http://schemas.android.com/aapt"
+    android:width="100dp" android:height="100dp"
+    android:viewportWidth="100" android:viewportHeight="100">
+  <path android:pathData="M0,0h100v100h-100z">
+    <aapt:attr name="android:fillColor">` + gradient + `</aapt:attr>
+  </path>
+</vector>`
+}
+
+func TestWriteDeterministic(t *testing.T) {
+	// The colors are parsed anew each time and reference each other.
+	colors := `<resources>
+	<color name="red">#ff0000</color>
+	<color name="green">@color/red</color>
+	<color name="blue">#800000ff</color>
+</resources>`
+	xmlData := testVector(`
+  <path android:fillColor="@color/red" android:pathData="M10,10h40v40h-40z"/>
+  <path android:fillColor="@color/green" android:pathData="M50,10h40v40h-40z"/>
+  <path android:strokeColor="@color/blue" android:strokeWidth="3" android:pathData="M10,70h80"/>`)
+	write := func(format Format) []byte {
+		opts := Options{Colors: ColorDefs{}}
+		if err := ParseColors([]byte(colors), opts.Colors); err != nil {
+			t.Fatal(err)
+		}
+		c, err := Convert([]byte(xmlData), opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var buf bytes.Buffer
+		if _, err := Write(&buf, c, format, opts); err != nil {
+			t.Fatal(err)
+		}
+		return buf.Bytes()
+	}
+	for _, format := range []Format{FormatPNG, FormatWebP, FormatICO, FormatICNS} {
+		t.Run(string(format), func(t *testing.T) {
+			first := write(format)
+			for i := 0; i < 5; i++ {
+				if !bytes.Equal(write(format), first) {
+					t.Fatalf("output %d differs from the first", i+1)
+				}
+			}
+		})
+	}
+}
+
+// launcherBackground is a full bleed adaptive icon background with absolute
+// H and V commands and a capital Z, like the one of the seam report.
+const launcherBackground = `<vector xmlns:android="http://schemas.android.com/apk/res/android"
+    android:width="108dp" android:height="108dp"
+    android:viewportWidth="108" android:viewportHeight="108">
+  <path android:fillColor="#3DDC84" android:pathData="M0,0H108V108H0Z"/>
+  <path android:fillColor="#00000000" android:strokeColor="#33FFFFFF" android:strokeWidth="0.8"
+      android:pathData="M9,0L9,108M19,0L19,108M29,0L29,108M0,9L108,9M0,19L108,19"/>
+</vector>`
+
+func TestSnapScale(t *testing.T) {
+	tests := []struct {
+		name   string
+		vector string
+		opts   Options
+		// seam is whether the edges are only partly covered.
+		seam bool
+	}{
+		{"fractional size", launcherBackground, Options{Scale: 0.48}, true},
+		{"snapped", launcherBackground, Options{Scale: 0.48, SnapScale: true}, false},
+		{"snapped anisotropic", launcherBackground, Options{Scale: 0.48, ScaleY: 0.37, SnapScale: true}, false},
+		{"whole size", launcherBackground, Options{Scale: 1.5}, false},
+		{
+			name: "snapped wide viewport",
+			vector: `<vector xmlns:android="http://schemas.android.com/apk/res/android"
+    android:width="108dp" android:height="54dp"
+    android:viewportWidth="432" android:viewportHeight="108">
+  <path android:fillColor="#3DDC84" android:pathData="M0,0H432V108H0Z"/>
+</vector>`,
+			opts: Options{Scale: 0.7, SnapScale: true},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			img := render(t, test.vector, test.opts)
+			size := img.Bounds().Size()
+			seam := false
+			for x := 0; x < size.X; x++ {
+				seam = seam || alphaAt(img, x, 0) != 255 || alphaAt(img, x, size.Y-1) != 255
+			}
+			for y := 0; y < size.Y; y++ {
+				seam = seam || alphaAt(img, 0, y) != 255 || alphaAt(img, size.X-1, y) != 255
+			}
+			if seam != test.seam {
+				t.Errorf("got seam %v at the edges of the %v image, want %v", seam, size, test.seam)
+			}
+		})
+	}
+}
+
+func TestWriteWebP(t *testing.T) {
+	xmlData := testVector(`
+  <path android:fillColor="#ff0000" android:pathData="M10,10h40v40h-40z"/>
+  <path android:fillColor="#800000ff" android:pathData="M30,30h40v40h-40z"/>`)
+	tests := []struct {
+		name string
+		opts Options
+	}{
+		{"default", Options{}},
+		{"fastest", Options{Quality: 1}},
+		{"smallest", Options{Quality: 100}},
+		{"flattened", Options{Flatten: color.White}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c, err := Convert([]byte(xmlData), test.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var buf bytes.Buffer
+			if _, err := Write(&buf, c, FormatWebP, test.opts); err != nil {
+				t.Fatal(err)
+			}
+			img, err := webp.Decode(&buf)
+			if err != nil {
+				t.Fatal(err)
+			}
+			// The image is lossless, so it has the pixels of the PNG.
+			want := rasterize(c, 1, test.opts.Flatten, &test.opts)
+			if img.Bounds() != want.Bounds() {
+				t.Fatalf("got bounds %v, want %v", img.Bounds(), want.Bounds())
+			}
+			for _, p := range []image.Point{{5, 5}, {20, 20}, {40, 40}, {60, 60}, {50, 10}} {
+				got := color.NRGBAModel.Convert(img.At(p.X, p.Y))
+				if w := color.NRGBAModel.Convert(want.At(p.X, p.Y)); got != w {
+					t.Errorf("got %v at %v, want %v", got, p, w)
+				}
+			}
+		})
+	}
+}
+
+func TestParseFormat(t *testing.T) {
+	tests := []struct {
+		name string
+		want Format
+	}{
+		{"png", FormatPNG},
+		{"JPG", FormatJPEG},
+		{"jpeg", FormatJPEG},
+		{"webp", FormatWebP},
+		{"svg", FormatSVG},
+		{"ico", FormatICO},
+		{"icns", FormatICNS},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got, err := ParseFormat(test.name); err != nil || got != test.want {
+				t.Errorf("got %q, %v, want %q", got, err, test.want)
+			}
+			if got := FormatFromPath("icon." + test.name); got != test.want {
+				t.Errorf("got %q from the extension, want %q", got, test.want)
+			}
+		})
+	}
+	if _, err := ParseFormat("gif"); err == nil || !strings.Contains(err.Error(), `unknown format "gif"`) {
+		t.Errorf("got error %v for gif", err)
+	}
+}
@@ -0,0 +1,65 @@
+package vectopng
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// WriteFS is a file system that SaveFS writes the images to. The file names
+// are built like for Save, so they follow the path separator of the
+// operating system.
+type WriteFS interface {
+	// Create creates or truncates the named file. Missing parent
+	// directories are created if the file system has directories.
+	Create(name string) (io.WriteCloser, error)
+}
+
+// osFS is the file system of the operating system used by Save.
+type osFS struct{}
+
+func (osFS) Create(name string) (io.WriteCloser, error) {
+	if err := os.MkdirAll(filepath.Dir(name), 0o755); err != nil {
+		return nil, err
+	}
+	return os.Create(name)
+}
+
+// MemFS is an in-memory WriteFS, for example to test conversions without
+// touching the disk. A file is added once its writer is closed. It is safe
+// for concurrent use.
+type MemFS struct {
+	mu    sync.Mutex
+	files map[string][]byte
+}
+
+func (m *MemFS) Create(name string) (io.WriteCloser, error) {
+	return &memFile{fs: m, name: name}, nil
+}
+
+// File returns the content of the named file and whether it exists.
+func (m *MemFS) File(name string) ([]byte, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	data, ok := m.files[name]
+	return data, ok
+}
+
+// memFile is a file of a MemFS being written.
+type memFile struct {
+	bytes.Buffer
+	fs   *MemFS
+	name string
+}
+
+func (f *memFile) Close() error {
+	f.fs.mu.Lock()
+	defer f.fs.mu.Unlock()
+	if f.fs.files == nil {
+		f.fs.files = make(map[string][]byte)
+	}
+	f.fs.files[f.name] = f.Bytes()
+	return nil
+}
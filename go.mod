@@ -1,8 +1,13 @@
 module perron2.ch/vectopng
 
-go 1.21.0
+go 1.22.2
 
-require github.com/tdewolff/canvas v0.0.0-20230819123001-a68886ffa13f
+require (
+	github.com/HugoSmits86/nativewebp v1.3.0
+	github.com/fsnotify/fsnotify v1.7.0
+	github.com/tdewolff/canvas v0.0.0-20230819123001-a68886ffa13f
+	golang.org/x/image v0.24.0
+)
 
 require (
 	github.com/ByteArena/poly2tri-go v0.0.0-20170716161910-d102ad91854f // indirect
@@ -19,9 +24,8 @@ require (
 	github.com/tdewolff/minify/v2 v2.12.8 // indirect
 	github.com/tdewolff/parse/v2 v2.6.7 // indirect
 	github.com/wcharczuk/go-chart/v2 v2.1.0 // indirect
-	golang.org/x/image v0.11.0 // indirect
 	golang.org/x/sys v0.11.0 // indirect
-	golang.org/x/text v0.12.0 // indirect
+	golang.org/x/text v0.22.0 // indirect
 	gonum.org/v1/plot v0.12.0 // indirect
 	star-tex.org/x/tex v0.4.0 // indirect
 )
@@ -0,0 +1,272 @@
+package vectopng
+
+import (
+	"encoding/xml"
+	"fmt"
+	"image/color"
+	"math"
+	"slices"
+	"sort"
+
+	"github.com/tdewolff/canvas"
+)
+
+// aaptAttr is an <aapt:attr> element, which gives the value of the attribute
+// of its parent element as child element.
+type aaptAttr struct {
+	Name     string          `xml:"name,attr" json:"name"`
+	Gradient *vectorGradient `xml:"gradient" json:"gradient,omitempty"`
+}
+
+// vectorGradient is a <gradient> element, which is given inline as
+// <aapt:attr> of a color attribute. The coordinates are in the viewport of
+// the vector.
+type vectorGradient struct {
+	Type     string  `xml:"type,attr" json:"type,omitempty"`
+	TileMode string  `xml:"tileMode,attr" json:"tileMode,omitempty"`
+	StartX   float64 `xml:"startX,attr" json:"startX"`
+	StartY   float64 `xml:"startY,attr" json:"startY"`
+	EndX     float64 `xml:"endX,attr" json:"endX"`
+	EndY     float64 `xml:"endY,attr" json:"endY"`
+	// CenterX and CenterY are the center of radial and sweep gradients.
+	CenterX        float64 `xml:"centerX,attr" json:"centerX,omitempty"`
+	CenterY        float64 `xml:"centerY,attr" json:"centerY,omitempty"`
+	GradientRadius float64 `xml:"gradientRadius,attr" json:"gradientRadius,omitempty"`
+	// StartColor, CenterColor and EndColor define the colors at the offsets
+	// 0, 0.5 and 1 if the gradient has no items. A missing start or end
+	// color is transparent, a missing center color leaves out its stop.
+	StartColor  string         `xml:"startColor,attr" json:"startColor,omitempty"`
+	CenterColor string         `xml:"centerColor,attr" json:"centerColor,omitempty"`
+	EndColor    string         `xml:"endColor,attr" json:"endColor,omitempty"`
+	Items       []gradientItem `xml:"item" json:"items,omitempty"`
+}
+
+func (g *vectorGradient) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	type plainGradient vectorGradient
+	start.Attr = androidAttrs(start.Attr)
+	return d.DecodeElement((*plainGradient)(g), &start)
+}
+
+// gradientItem is a color stop of a gradient.
+type gradientItem struct {
+	Offset float64 `xml:"offset,attr" json:"offset"`
+	Color  string  `xml:"color,attr" json:"color"`
+}
+
+func (item *gradientItem) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	type plainItem gradientItem
+	start.Attr = androidAttrs(start.Attr)
+	return d.DecodeElement((*plainItem)(item), &start)
+}
+
+// kind returns the type of the gradient, which defaults to linear.
+func (g *vectorGradient) kind() string {
+	if g.Type == "" {
+		return "linear"
+	}
+	return g.Type
+}
+
+// validate checks the type, the tile mode and the color stops of the
+// gradient and sorts the stops by offset. Without stops, the gradient is
+// defined by its start and end colors instead, so a single stop is an error.
+func (g *vectorGradient) validate() error {
+	switch g.Type {
+	case "", "linear", "radial", "sweep":
+	default:
+		return fmt.Errorf("unsupported gradient type \"%s\"", g.Type)
+	}
+	if _, err := parseTileMode(g.TileMode); err != nil {
+		return err
+	}
+	if len(g.Items) == 1 {
+		return fmt.Errorf("gradient has a single item, expected at least two")
+	}
+	for i, item := range g.Items {
+		if math.IsNaN(item.Offset) || item.Offset < 0 || item.Offset > 1 {
+			return fmt.Errorf("offset %g of gradient item %d is outside of 0 to 1", item.Offset, i)
+		} else if item.Color == "" {
+			return fmt.Errorf("gradient item %d has no color", i)
+		}
+	}
+	// Items of the same offset keep their order, which gives a hard edge.
+	sort.SliceStable(g.Items, func(i, j int) bool {
+		return g.Items[i].Offset < g.Items[j].Offset
+	})
+	return nil
+}
+
+// tileMode defines how a gradient continues beyond its end points, as set
+// by the android:tileMode attribute of a gradient.
+type tileMode string
+
+const (
+	tileClamp  tileMode = "clamp"
+	tileRepeat tileMode = "repeat"
+	tileMirror tileMode = "mirror"
+)
+
+// parseTileMode parses the android:tileMode attribute, which defaults to
+// clamp.
+func parseTileMode(s string) (tileMode, error) {
+	switch m := tileMode(s); m {
+	case "":
+		return tileClamp, nil
+	case tileClamp, tileRepeat, tileMirror:
+		return m, nil
+	}
+	return "", fmt.Errorf("unsupported tileMode \"%s\"", s)
+}
+
+// apply maps the position t along a gradient to the range [0, 1]. Clamp
+// extends the end colors, repeat starts over at each end and mirror reverses
+// the direction at each end.
+func (m tileMode) apply(t float64) float64 {
+	switch m {
+	case tileRepeat:
+		return t - math.Floor(t)
+	case tileMirror:
+		t = math.Mod(math.Abs(t), 2)
+		if t > 1 {
+			t = 2 - t
+		}
+		return t
+	}
+	return math.Max(0, math.Min(1, t))
+}
+
+// gradient returns the canvas gradient of g in viewport coordinates. The
+// colors of the stops are resolved, multiplied by alpha and tinted like the
+// colors of paths.
+func (r *renderer) gradient(g *vectorGradient, alpha float64) (canvas.Gradient, error) {
+	if err := g.validate(); err != nil {
+		return nil, err
+	}
+	if g.kind() == "radial" && !(g.GradientRadius > 0) {
+		return nil, fmt.Errorf("radial gradient needs a gradientRadius greater than zero")
+	}
+	items := g.Items
+	if len(items) == 0 {
+		if g.StartColor == "" && g.CenterColor == "" && g.EndColor == "" {
+			return nil, fmt.Errorf("gradient has neither items nor a startColor, centerColor or endColor")
+		}
+		items = []gradientItem{{Offset: 0, Color: g.StartColor}, {Offset: 1, Color: g.EndColor}}
+		if g.CenterColor != "" {
+			items = slices.Insert(items, 1, gradientItem{Offset: 0.5, Color: g.CenterColor})
+		}
+	}
+
+	offsets := make([]float64, len(items))
+	colors := make([]color.Color, len(items))
+	for i, item := range items {
+		offsets[i] = item.Offset
+		colors[i] = canvas.Transparent
+		if item.Color == "" {
+			continue
+		}
+		c, err := r.opts.color(item.Color, &r.stats)
+		if err != nil {
+			return nil, err
+		}
+		c, _ = scaleAlpha(c, alpha)
+		colors[i] = r.tintColor(c)
+	}
+	stops := gradientStops(offsets, colors, r.opts.GradientInterpolation)
+	mode, _ := parseTileMode(g.TileMode)
+	start, end := canvas.Point{X: g.StartX, Y: g.StartY}, canvas.Point{X: g.EndX, Y: g.EndY}
+	center := canvas.Point{X: g.CenterX, Y: g.CenterY}
+	switch {
+	case g.kind() == "sweep":
+		return newViewportGradient(stops, tileClamp, func(p canvas.Point) float64 {
+			// The y axis of the viewport points down, so the angle grows
+			// clockwise.
+			p = p.Sub(center)
+			t := math.Atan2(p.Y, p.X) / (2 * math.Pi)
+			if t < 0 {
+				t++
+			}
+			return t
+		}), nil
+	case g.kind() == "radial":
+		radial := canvas.NewRadialGradient(center, 0, center, g.GradientRadius)
+		radial.Stops = stops
+		if mode == tileClamp {
+			return radial, nil
+		}
+		gradient := newViewportGradient(stops, mode, func(p canvas.Point) float64 {
+			return p.Sub(center).Length() / g.GradientRadius
+		})
+		gradient.svg = radial
+		return gradient, nil
+	}
+	linear := canvas.NewLinearGradient(start, end)
+	linear.Stops = stops
+	if mode == tileClamp {
+		return linear, nil
+	}
+	d := end.Sub(start)
+	gradient := newViewportGradient(stops, mode, func(p canvas.Point) float64 {
+		if d2 := d.Dot(d); d2 > 0 {
+			return p.Sub(start).Dot(d) / d2
+		}
+		return 0
+	})
+	gradient.svg = linear
+	return gradient, nil
+}
+
+// viewportGradient is a gradient whose position is computed in viewport
+// coordinates, for the gradients that canvas does not have: sweep
+// gradients, which go around the center clockwise from the positive x axis
+// like on Android, and linear and radial gradients with the repeat and
+// mirror tile modes.
+type viewportGradient struct {
+	canvas.Stops
+	mode tileMode
+	// position returns the position along the gradient of a point in
+	// viewport coordinates, before the tile mode is applied.
+	position func(p canvas.Point) float64
+	// inv maps the canvas coordinates given to At back to viewport
+	// coordinates.
+	inv canvas.Matrix
+	// svg is the linear or radial gradient with the same geometry, which
+	// SVG repeats or mirrors with its spreadMethod. It is nil for sweep
+	// gradients.
+	svg canvas.Gradient
+}
+
+func newViewportGradient(stops canvas.Stops, mode tileMode, position func(p canvas.Point) float64) *viewportGradient {
+	return &viewportGradient{Stops: stops, mode: mode, position: position, inv: canvas.Identity}
+}
+
+// SetView sets the view, which moves the gradient into canvas coordinates.
+func (g *viewportGradient) SetView(view canvas.Matrix) canvas.Gradient {
+	if view == canvas.Identity {
+		return g
+	}
+	gradient := *g
+	gradient.inv = g.inv.Mul(view.Inv())
+	if g.svg != nil {
+		gradient.svg = g.svg.SetView(view)
+	}
+	return &gradient
+}
+
+// SetColorSpace converts the colors of the stops to the color space.
+func (g *viewportGradient) SetColorSpace(colorSpace canvas.ColorSpace) canvas.Gradient {
+	if _, ok := colorSpace.(canvas.LinearColorSpace); ok {
+		return g
+	}
+	gradient := *g
+	gradient.Stops = make(canvas.Stops, len(g.Stops))
+	for i, stop := range g.Stops {
+		gradient.Stops[i] = canvas.Stop{Offset: stop.Offset, Color: colorSpace.ToLinear(stop.Color)}
+	}
+	return &gradient
+}
+
+// At returns the color at the position in canvas coordinates.
+func (g *viewportGradient) At(x, y float64) color.RGBA {
+	p := g.inv.Dot(canvas.Point{X: x, Y: y})
+	return g.Stops.At(g.mode.apply(g.position(p)))
+}
@@ -0,0 +1,101 @@
+package vectopng
+
+import (
+	"image"
+	"strings"
+	"testing"
+)
+
+func TestGradientErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		gradient string
+		want     string
+	}{
+		{
+			name:     "unsupported type",
+			gradient: `<gradient android:type="conic" android:startColor="#ff0000" android:endColor="#0000ff"/>`,
+			want:     `invalid gradient of the fillColor of path 0: unsupported gradient type "conic"`,
+		},
+		{
+			name:     "unsupported tile mode",
+			gradient: `<gradient android:tileMode="wrap" android:startColor="#ff0000" android:endColor="#0000ff"/>`,
+			want:     `unsupported tileMode "wrap"`,
+		},
+		{
+			name:     "no colors",
+			gradient: `<gradient android:startX="0" android:endX="100"/>`,
+			want:     "gradient has neither items nor a startColor, centerColor or endColor",
+		},
+		{
+			name: "single item",
+			gradient: `<gradient android:endX="100">
+        <item android:offset="0" android:color="#ff0000"/>
+      </gradient>`,
+			want: "gradient has a single item, expected at least two",
+		},
+		{
+			name: "offset above 1",
+			gradient: `<gradient android:endX="100">
+        <item android:offset="0" android:color="#ff0000"/>
+        <item android:offset="1.5" android:color="#0000ff"/>
+      </gradient>`,
+			want: "offset 1.5 of gradient item 1 is outside of 0 to 1",
+		},
+		{
+			name: "negative offset",
+			gradient: `<gradient android:endX="100">
+        <item android:offset="-0.1" android:color="#ff0000"/>
+        <item android:offset="1" android:color="#0000ff"/>
+      </gradient>`,
+			want: "offset -0.1 of gradient item 0 is outside of 0 to 1",
+		},
+		{
+			name: "item without color",
+			gradient: `<gradient android:endX="100">
+        <item android:offset="0" android:color="#ff0000"/>
+        <item android:offset="1"/>
+      </gradient>`,
+			want: "gradient item 1 has no color",
+		},
+		{
+			name: "unresolved item color",
+			gradient: `<gradient android:endX="100">
+        <item android:offset="0" android:color="#ff0000"/>
+        <item android:offset="1" android:color="@color/missing"/>
+      </gradient>`,
+			want: `unresolved color reference "@color/missing"`,
+		},
+		{
+			name:     "radial without radius",
+			gradient: `<gradient android:type="radial" android:centerX="50" android:centerY="50" android:startColor="#ff0000" android:endColor="#0000ff"/>`,
+			want:     "radial gradient needs a gradientRadius greater than zero",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := Convert([]byte(testGradientVector(test.gradient)), Options{})
+			if err == nil || !strings.Contains(err.Error(), test.want) {
+				t.Errorf("got error %v, want %q", err, test.want)
+			}
+		})
+	}
+}
+
+func TestGradientItemOrder(t *testing.T) {
+	sorted := render(t, testGradientVector(`<gradient android:endX="100">
+        <item android:offset="0" android:color="#ff0000"/>
+        <item android:offset="0.5" android:color="#00ff00"/>
+        <item android:offset="1" android:color="#0000ff"/>
+      </gradient>`), Options{}).(*image.RGBA)
+	unsorted := render(t, testGradientVector(`<gradient android:endX="100">
+        <item android:offset="1" android:color="#0000ff"/>
+        <item android:offset="0" android:color="#ff0000"/>
+        <item android:offset="0.5" android:color="#00ff00"/>
+      </gradient>`), Options{}).(*image.RGBA)
+	for _, x := range []int{10, 50, 90} {
+		if got, want := unsorted.RGBAAt(x, 50), sorted.RGBAAt(x, 50); got != want {
+			t.Errorf("got %v at x = %d, want %v", got, x, want)
+		}
+	}
+}
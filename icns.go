@@ -0,0 +1,64 @@
+package vectopng
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+
+	"github.com/tdewolff/canvas"
+)
+
+// icnsTypes are the PNG icon types of a macOS iconset (16 to 512 px, each
+// also in @2x) with their pixel sizes.
+var icnsTypes = []struct {
+	Type string
+	Size int
+}{
+	{"icp4", 16},
+	{"ic11", 32},
+	{"icp5", 32},
+	{"ic12", 64},
+	{"ic07", 128},
+	{"ic13", 256},
+	{"ic08", 256},
+	{"ic14", 512},
+	{"ic09", 512},
+	{"ic10", 1024},
+}
+
+// writeICNS writes the canvas as ICNS file containing a table of contents
+// followed by a square PNG image for each icon type. Images of the same size
+// are rendered only once.
+func writeICNS(w io.Writer, c *canvas.Canvas, opts *Options) error {
+	images := make(map[int][]byte)
+	for _, t := range icnsTypes {
+		if _, ok := images[t.Size]; ok {
+			continue
+		}
+		var buf bytes.Buffer
+		if err := pngEncoder.Encode(&buf, squareImage(c, t.Size, opts)); err != nil {
+			return err
+		}
+		images[t.Size] = buf.Bytes()
+	}
+
+	var toc, icons bytes.Buffer
+	for _, t := range icnsTypes {
+		length := uint32(8 + len(images[t.Size]))
+		toc.WriteString(t.Type)
+		binary.Write(&toc, binary.BigEndian, length)
+		icons.WriteString(t.Type)
+		binary.Write(&icons, binary.BigEndian, length)
+		icons.Write(images[t.Size])
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("icns")
+	binary.Write(&buf, binary.BigEndian, uint32(8+8+toc.Len()+icons.Len()))
+	buf.WriteString("TOC ")
+	binary.Write(&buf, binary.BigEndian, uint32(8+toc.Len()))
+	buf.Write(toc.Bytes())
+	buf.Write(icons.Bytes())
+	_, err := w.Write(buf.Bytes())
+	return err
+}
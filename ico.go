@@ -0,0 +1,76 @@
+package vectopng
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"image"
+	"image/draw"
+	"io"
+	"math"
+
+	"github.com/tdewolff/canvas"
+)
+
+// DefaultICOSizes are the icon sizes written to ICO files by default.
+var DefaultICOSizes = []int{16, 32, 48, 64, 128, 256}
+
+// writeICO writes the canvas as ICO file containing a square PNG image for
+// each size. Non-square canvases are centered within the images.
+func writeICO(w io.Writer, c *canvas.Canvas, sizes []int, opts *Options) error {
+	images := make([][]byte, len(sizes))
+	for i, size := range sizes {
+		if size < 1 || size > 256 {
+			return fmt.Errorf("invalid ICO size %d (must be between 1 and 256)", size)
+		}
+		var buf bytes.Buffer
+		if err := pngEncoder.Encode(&buf, squareImage(c, size, opts)); err != nil {
+			return err
+		}
+		images[i] = buf.Bytes()
+	}
+
+	header := []uint16{0, 1, uint16(len(sizes))}
+	if err := binary.Write(w, binary.LittleEndian, header); err != nil {
+		return err
+	}
+	offset := 6 + 16*len(sizes)
+	for i, size := range sizes {
+		entry := struct {
+			Width, Height, Colors, Reserved uint8
+			Planes, BitCount                uint16
+			Size, Offset                    uint32
+		}{
+			Width:    uint8(size % 256),
+			Height:   uint8(size % 256),
+			Planes:   1,
+			BitCount: 32,
+			Size:     uint32(len(images[i])),
+			Offset:   uint32(offset),
+		}
+		if err := binary.Write(w, binary.LittleEndian, entry); err != nil {
+			return err
+		}
+		offset += len(images[i])
+	}
+	for _, data := range images {
+		if _, err := w.Write(data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// squareImage rasterizes the canvas to fit a square image of the given size.
+func squareImage(c *canvas.Canvas, size int, opts *Options) image.Image {
+	img := rasterize(c, float64(size)/math.Max(c.W, c.H), nil, opts)
+	bounds := img.Bounds()
+	if bounds.Dx() == size && bounds.Dy() == size {
+		return img
+	}
+
+	square := image.NewRGBA(image.Rect(0, 0, size, size))
+	offset := image.Pt((size-bounds.Dx())/2, (size-bounds.Dy())/2)
+	draw.Draw(square, bounds.Add(offset), img, bounds.Min, draw.Src)
+	return square
+}
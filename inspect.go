@@ -0,0 +1,81 @@
+package vectopng
+
+import (
+	"encoding/json"
+)
+
+// inspection is the JSON representation of a parsed vector drawable with
+// the dimensions in dp.
+type inspection struct {
+	Width          float64      `json:"width"`
+	Height         float64      `json:"height"`
+	ViewportWidth  float64      `json:"viewportWidth"`
+	ViewportHeight float64      `json:"viewportHeight"`
+	Tint           string       `json:"tint,omitempty"`
+	TintMode       string       `json:"tintMode,omitempty"`
+	AutoMirrored   bool         `json:"autoMirrored,omitempty"`
+	Alpha          float64      `json:"alpha"`
+	Children       []vectorNode `json:"children"`
+}
+
+// Inspect parses the given Android vector drawable without rendering it and
+// returns what was understood as indented JSON. Unsupported elements are
+// left out.
+func Inspect(xmlData []byte) ([]byte, error) {
+	vec, err := decodeVector(xmlData)
+	if err != nil {
+		return nil, err
+	}
+	width, err := parseDimension(vec.Width, "width", 0)
+	if err != nil {
+		return nil, err
+	}
+	height, err := parseDimension(vec.Height, "height", 0)
+	if err != nil {
+		return nil, err
+	}
+
+	return json.MarshalIndent(inspection{
+		Width:          width,
+		Height:         height,
+		ViewportWidth:  vec.ViewportWidth,
+		ViewportHeight: vec.ViewportHeight,
+		Tint:           vec.Tint,
+		TintMode:       vec.TintMode,
+		AutoMirrored:   vec.AutoMirrored,
+		Alpha:          vec.Alpha,
+		Children:       supportedNodes(vec.Children),
+	}, "", "  ")
+}
+
+func (n vectorNode) MarshalJSON() ([]byte, error) {
+	if n.Group != nil {
+		group := *n.Group
+		group.Children = supportedNodes(group.Children)
+		return json.Marshal(struct {
+			Type string `json:"type"`
+			vectorGroup
+		}{"group", group})
+	}
+	if n.ClipPath != nil {
+		return json.Marshal(struct {
+			Type string `json:"type"`
+			*vectorClipPath
+		}{"clip-path", n.ClipPath})
+	}
+	return json.Marshal(struct {
+		Type string `json:"type"`
+		*vectorPath
+	}{"path", n.Path})
+}
+
+// supportedNodes returns the nodes without the unsupported elements.
+func supportedNodes(nodes []vectorNode) []vectorNode {
+	supported := []vectorNode{}
+	for _, node := range nodes {
+		if node.Path != nil || node.Group != nil || node.ClipPath != nil {
+			supported = append(supported, node)
+		}
+	}
+	return supported
+}
@@ -0,0 +1,135 @@
+package vectopng
+
+import (
+	"fmt"
+	"image/color"
+	"math"
+	"strings"
+
+	"github.com/tdewolff/canvas"
+)
+
+// Interpolation is the color space in which the colors between the stops of
+// a gradient are computed.
+type Interpolation string
+
+// The supported gradient interpolations.
+const (
+	// InterpolateSRGB mixes the sRGB values directly like Android.
+	InterpolateSRGB Interpolation = "srgb"
+	// InterpolateLinear mixes the light intensities in linear RGB.
+	InterpolateLinear Interpolation = "linear"
+	// InterpolateOkLab mixes in the perceptual OkLab color space, which
+	// avoids muddy midtones between saturated colors.
+	InterpolateOkLab Interpolation = "oklab"
+)
+
+// ParseInterpolation parses the name of a gradient interpolation.
+func ParseInterpolation(s string) (Interpolation, error) {
+	switch i := Interpolation(strings.ToLower(s)); i {
+	case InterpolateSRGB, InterpolateLinear, InterpolateOkLab:
+		return i, nil
+	}
+	return "", fmt.Errorf("unsupported gradient interpolation \"%s\"", s)
+}
+
+// interpolationSteps is the number of stops each section between two stops
+// is divided into for interpolations other than sRGB, since the gradients
+// of canvas always mix sRGB values.
+const interpolationSteps = 16
+
+// gradientStops returns the canvas stops for the colors at the offsets,
+// which are sorted. Other interpolations than sRGB are approximated by
+// intermediate stops. Stops of the same offset are kept, which gives a hard
+// edge.
+func gradientStops(offsets []float64, colors []color.Color, interpolation Interpolation) canvas.Stops {
+	stops := make(canvas.Stops, 0, len(offsets))
+	for i := range offsets {
+		if i > 0 && interpolation != "" && interpolation != InterpolateSRGB && offsets[i] > offsets[i-1] {
+			for step := 1; step < interpolationSteps; step++ {
+				t := float64(step) / interpolationSteps
+				offset := offsets[i-1] + t*(offsets[i]-offsets[i-1])
+				stops = append(stops, canvas.Stop{Offset: offset, Color: mixColors(colors[i-1], colors[i], t, interpolation)})
+			}
+		}
+		stops = append(stops, canvas.Stop{Offset: offsets[i], Color: color.RGBAModel.Convert(colors[i]).(color.RGBA)})
+	}
+	return stops
+}
+
+// mixColors mixes the colors a and b by t in the color space of the
+// interpolation. The alpha is mixed linearly, the color channels without
+// premultiplied alpha.
+func mixColors(a color.Color, b color.Color, t float64, interpolation Interpolation) color.RGBA {
+	ca := color.NRGBAModel.Convert(a).(color.NRGBA)
+	cb := color.NRGBAModel.Convert(b).(color.NRGBA)
+	va := [3]float64{float64(ca.R) / 255, float64(ca.G) / 255, float64(ca.B) / 255}
+	vb := [3]float64{float64(cb.R) / 255, float64(cb.G) / 255, float64(cb.B) / 255}
+	convert, back := func(v [3]float64) [3]float64 { return v }, func(v [3]float64) [3]float64 { return v }
+	switch interpolation {
+	case InterpolateLinear:
+		convert, back = srgbToLinear, linearToSRGB
+	case InterpolateOkLab:
+		convert = func(v [3]float64) [3]float64 { return linearToOkLab(srgbToLinear(v)) }
+		back = func(v [3]float64) [3]float64 { return linearToSRGB(okLabToLinear(v)) }
+	}
+	va, vb = convert(va), convert(vb)
+	var v [3]float64
+	for i := range v {
+		v[i] = va[i] + t*(vb[i]-va[i])
+	}
+	v = back(v)
+	channel := func(x float64) uint8 {
+		return uint8(math.Round(255 * math.Max(0, math.Min(1, x))))
+	}
+	alpha := float64(ca.A) + t*(float64(cb.A)-float64(ca.A))
+	mixed := color.NRGBA{R: channel(v[0]), G: channel(v[1]), B: channel(v[2]), A: uint8(math.Round(alpha))}
+	return color.RGBAModel.Convert(mixed).(color.RGBA)
+}
+
+func srgbToLinear(v [3]float64) [3]float64 {
+	for i, x := range v {
+		if x <= 0.04045 {
+			v[i] = x / 12.92
+		} else {
+			v[i] = math.Pow((x+0.055)/1.055, 2.4)
+		}
+	}
+	return v
+}
+
+func linearToSRGB(v [3]float64) [3]float64 {
+	for i, x := range v {
+		if x <= 0.0031308 {
+			v[i] = 12.92 * x
+		} else {
+			v[i] = 1.055*math.Pow(x, 1/2.4) - 0.055
+		}
+	}
+	return v
+}
+
+// linearToOkLab and okLabToLinear convert between linear sRGB and OkLab
+// with the matrices published by Björn Ottosson.
+func linearToOkLab(v [3]float64) [3]float64 {
+	l := math.Cbrt(0.4122214708*v[0] + 0.5363325363*v[1] + 0.0514459929*v[2])
+	m := math.Cbrt(0.2119034982*v[0] + 0.6806995451*v[1] + 0.1073969566*v[2])
+	s := math.Cbrt(0.0883024619*v[0] + 0.2817188376*v[1] + 0.6299787005*v[2])
+	return [3]float64{
+		0.2104542553*l + 0.7936177850*m - 0.0040720468*s,
+		1.9779984951*l - 2.4285922050*m + 0.4505937099*s,
+		0.0259040371*l + 0.7827717662*m - 0.8086757660*s,
+	}
+}
+
+func okLabToLinear(v [3]float64) [3]float64 {
+	l := v[0] + 0.3963377774*v[1] + 0.2158037573*v[2]
+	m := v[0] - 0.1055613458*v[1] - 0.0638541728*v[2]
+	s := v[0] - 0.0894841775*v[1] - 1.2914855480*v[2]
+	l, m, s = l*l*l, m*m*m, s*s*s
+	return [3]float64{
+		4.0767416621*l - 3.3077115913*m + 0.2309699292*s,
+		-1.2684380046*l + 2.6097574011*m - 0.3413193965*s,
+		-0.0041960863*l - 0.7034186147*m + 1.7076147010*s,
+	}
+}
@@ -0,0 +1,65 @@
+package vectopng
+
+import (
+	"image"
+	"image/color"
+	"strings"
+	"testing"
+)
+
+func TestGradientInterpolation(t *testing.T) {
+	gradient := testGradientVector(`<gradient android:startX="0" android:endX="100" android:startColor="#ff0000" android:endColor="#00ff00"/>`)
+	tests := []struct {
+		interpolation Interpolation
+		quarter       color.RGBA
+		middle        color.RGBA
+	}{
+		{"", color.RGBA{191, 63, 0, 255}, color.RGBA{127, 127, 0, 255}},
+		{InterpolateSRGB, color.RGBA{191, 63, 0, 255}, color.RGBA{127, 127, 0, 255}},
+		// Mixing the light intensities lightens the middle.
+		{InterpolateLinear, color.RGBA{225, 137, 0, 255}, color.RGBA{188, 188, 0, 255}},
+		// OkLab keeps the middle orange rather than a muddy olive.
+		{InterpolateOkLab, color.RGBA{237, 115, 0, 255}, color.RGBA{208, 168, 0, 255}},
+	}
+	for _, test := range tests {
+		t.Run(string(test.interpolation), func(t *testing.T) {
+			img := render(t, gradient, Options{GradientInterpolation: test.interpolation}).(*image.RGBA)
+			if got := img.RGBAAt(0, 50); got != (color.RGBA{255, 0, 0, 255}) {
+				t.Errorf("got %v at the start", got)
+			}
+			if got := img.RGBAAt(25, 50); got != test.quarter {
+				t.Errorf("got %v at a quarter, want %v", got, test.quarter)
+			}
+			if got := img.RGBAAt(50, 50); got != test.middle {
+				t.Errorf("got %v in the middle, want %v", got, test.middle)
+			}
+		})
+	}
+}
+
+func TestMixColors(t *testing.T) {
+	tests := []struct {
+		interpolation Interpolation
+		want          color.RGBA
+	}{
+		{InterpolateSRGB, color.RGBA{128, 128, 128, 255}},
+		{InterpolateLinear, color.RGBA{188, 188, 188, 255}},
+		{InterpolateOkLab, color.RGBA{99, 99, 99, 255}},
+	}
+	for _, test := range tests {
+		t.Run(string(test.interpolation), func(t *testing.T) {
+			if got := mixColors(color.Black, color.White, 0.5, test.interpolation); got != test.want {
+				t.Errorf("got %v halfway from black to white, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestParseInterpolation(t *testing.T) {
+	if got, err := ParseInterpolation("OkLab"); err != nil || got != InterpolateOkLab {
+		t.Errorf("got %q, %v, want %q", got, err, InterpolateOkLab)
+	}
+	if _, err := ParseInterpolation("hsl"); err == nil || !strings.Contains(err.Error(), `unsupported gradient interpolation "hsl"`) {
+		t.Errorf("got error %v for hsl", err)
+	}
+}
@@ -0,0 +1,55 @@
+package vectopng
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/tdewolff/canvas"
+)
+
+// Mask is the shape of an adaptive launcher icon.
+type Mask string
+
+// The supported adaptive icon masks.
+const (
+	MaskCircle   Mask = "circle"
+	MaskSquircle Mask = "squircle"
+	MaskRounded  Mask = "rounded"
+	MaskSquare   Mask = "square"
+)
+
+// maskPaths contains the mask shapes as used by Android in a 100x100
+// viewport.
+var maskPaths = map[Mask]string{
+	MaskCircle:   "M50,0A50,50,0,1,1,50,100A50,50,0,1,1,50,0Z",
+	MaskSquircle: "M50,0C10,0 0,10 0,50S10,100 50,100S100,90 100,50S90,0 50,0Z",
+	MaskRounded:  "M50,0L92,0C96.42,0 100,3.58 100,8L100,92C100,96.42 96.42,100 92,100L8,100C3.58,100 0,96.42 0,92L0,8C0,3.58 3.58,0 8,0Z",
+	MaskSquare:   "M0,0L100,0L100,100L0,100Z",
+}
+
+// ParseMask parses a mask name such as "circle", "squircle", "rounded" or
+// "square".
+func ParseMask(s string) (Mask, error) {
+	m := Mask(strings.ToLower(s))
+	if _, ok := maskPaths[m]; !ok {
+		return "", fmt.Errorf("unsupported mask \"%s\"", s)
+	}
+	return m, nil
+}
+
+// adaptiveSafeZone is the part of the width and height of an adaptive icon
+// that remains visible, 72 of 108 dp.
+const adaptiveSafeZone = 72.0 / 108.0
+
+// maskRect returns the visible area of an adaptive icon of the given size.
+func maskRect(width float64, height float64) canvas.Rect {
+	w := width * adaptiveSafeZone
+	h := height * adaptiveSafeZone
+	return canvas.Rect{X: (width - w) / 2, Y: (height - h) / 2, W: w, H: h}
+}
+
+// path returns the mask shape filling the rectangle.
+func (m Mask) path(rect canvas.Rect) *canvas.Path {
+	path := canvas.MustParseSVGPath(maskPaths[m])
+	return path.Transform(canvas.Identity.Translate(rect.X, rect.Y).Scale(rect.W/100, rect.H/100))
+}
@@ -0,0 +1,48 @@
+package vectopng
+
+import "testing"
+
+func TestAdaptiveMask(t *testing.T) {
+	background := `<vector xmlns:android="http://schemas.android.com/apk/res/android"
+    android:width="108dp" android:height="108dp"
+    android:viewportWidth="108" android:viewportHeight="108">
+  <path android:fillColor="#3DDC84" android:pathData="M0,0H108V108H0Z"/>
+</vector>`
+	tests := []struct {
+		mask    Mask
+		corners uint8
+	}{
+		{MaskCircle, 0},
+		{MaskSquircle, 0},
+		{MaskRounded, 0},
+		{MaskSquare, 255},
+	}
+	for _, test := range tests {
+		t.Run(string(test.mask), func(t *testing.T) {
+			img := render(t, background, Options{AdaptiveMask: test.mask, PixelWidth: 100})
+			if size := img.Bounds().Size(); size.X != 100 || size.Y != 100 {
+				t.Fatalf("got size %v, want 100x100", size)
+			}
+			for _, p := range [][2]int{{0, 0}, {99, 0}, {0, 99}, {99, 99}} {
+				if got := alphaAt(img, p[0], p[1]); got != test.corners {
+					t.Errorf("got alpha %d at the corner %v, want %d", got, p, test.corners)
+				}
+			}
+			if got := alphaAt(img, 50, 50); got != 255 {
+				t.Errorf("got alpha %d in the centre, want 255", got)
+			}
+			// All corners have the same shape, so the mask is symmetric to
+			// both axes and the diagonal.
+			for y := 0; y < 20; y++ {
+				for x := 0; x < 20; x++ {
+					a := int(alphaAt(img, x, y))
+					for _, p := range [][2]int{{99 - x, y}, {x, 99 - y}, {99 - x, 99 - y}, {y, x}} {
+						if b := int(alphaAt(img, p[0], p[1])); b-a > 2 || a-b > 2 {
+							t.Fatalf("got alpha %d at %d,%d but %d at %v", a, x, y, b, p)
+						}
+					}
+				}
+			}
+		})
+	}
+}
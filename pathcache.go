@@ -0,0 +1,239 @@
+package vectopng
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"image"
+	"math"
+	"sync"
+
+	"github.com/tdewolff/canvas"
+	"github.com/tdewolff/canvas/renderers/rasterizer"
+	imagevector "golang.org/x/image/vector"
+)
+
+// PathCache keeps the parsed path data and the rasterized paths of the
+// converted drawables, so that paths that recur in many drawables of a
+// batch, such as a shared badge, are parsed and rasterized only once. The
+// paths are keyed by their complete normalized path data and the rasterized
+// paths by the exact coordinates of the transformed path, its stroke and the
+// pixel grid, not by a hash of them, so different paths never share an
+// entry. The zero value is an empty cache, it is safe for concurrent use.
+type PathCache struct {
+	mu       sync.Mutex
+	paths    map[string]*canvas.Path
+	hits     int
+	tiles    map[string]*tile
+	tileHits int
+}
+
+// tile is the coverage of the fill and the stroke of a path in a region of
+// the image, in the 16 bit precision of the rasterizer. A tile caches a
+// path only once it is drawn the second time, so that paths that do not
+// recur do not keep their coverage. A nil fill or stroke is not drawn.
+type tile struct {
+	rect         image.Rectangle
+	fill, stroke *image.Alpha16
+}
+
+// Hits returns the number of paths that were taken from the cache.
+func (pc *PathCache) Hits() int {
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+	return pc.hits
+}
+
+// TileHits returns the number of rasterized paths that were taken from the
+// cache.
+func (pc *PathCache) TileHits() int {
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+	return pc.tileHits
+}
+
+// parse parses the path data or returns a copy of the path parsed before.
+func (pc *PathCache) parse(pathData string) (*canvas.Path, error) {
+	pc.mu.Lock()
+	path, ok := pc.paths[pathData]
+	if ok {
+		pc.hits++
+	}
+	pc.mu.Unlock()
+	if ok {
+		return path.Copy(), nil
+	}
+
+	path, err := canvas.ParseSVGPath(pathData)
+	if err != nil {
+		return nil, err
+	}
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+	if pc.paths == nil {
+		pc.paths = make(map[string]*canvas.Path)
+	}
+	pc.paths[pathData] = path.Copy()
+	return path, nil
+}
+
+// parsePath parses path data with the PathCache option if set.
+func (opts *Options) parsePath(pathData string) (*canvas.Path, error) {
+	if opts.PathCache == nil {
+		return canvas.ParseSVGPath(pathData)
+	}
+	return opts.PathCache.parse(pathData)
+}
+
+// tile returns the tile of the path drawn with the style and the matrix to
+// an image of the size and resolution. It rasterizes the path unless it was
+// cached.
+func (pc *PathCache) tile(path *canvas.Path, style canvas.Style, m canvas.Matrix, size image.Point, resolution canvas.Resolution) *tile {
+	key := tileKey(path, style, m, size, resolution)
+	pc.mu.Lock()
+	t := pc.tiles[key]
+	if t != nil {
+		pc.tileHits++
+	}
+	pc.mu.Unlock()
+	if t != nil {
+		return t
+	}
+
+	t = rasterizeTile(path, style, m, size, resolution)
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+	if pc.tiles == nil {
+		pc.tiles = make(map[string]*tile)
+	}
+	if _, seen := pc.tiles[key]; seen {
+		pc.tiles[key] = t
+	} else {
+		pc.tiles[key] = nil
+	}
+	return t
+}
+
+// tileKey returns the exact binary encoding of everything that the coverage
+// of a path depends on, which is all but its paint.
+func tileKey(path *canvas.Path, style canvas.Style, m canvas.Matrix, size image.Point, resolution canvas.Resolution) string {
+	var key bytes.Buffer
+	values := func(values ...float64) {
+		for _, v := range values {
+			binary.Write(&key, binary.LittleEndian, math.Float64bits(v))
+		}
+	}
+	values(float64(size.X), float64(size.Y), resolution.DPMM())
+	values(m[0][0], m[0][1], m[0][2], m[1][0], m[1][1], m[1][2])
+	if style.HasFill() {
+		key.WriteString("fill")
+	}
+	if style.HasStroke() {
+		fmt.Fprintf(&key, "stroke%#v%#v", style.StrokeCapper, style.StrokeJoiner)
+		values(style.StrokeWidth, style.DashOffset, float64(len(style.Dashes)))
+		values(style.Dashes...)
+	}
+	for scanner := path.Scanner(); scanner.Scan(); {
+		values(scanner.Values()...)
+	}
+	return key.String()
+}
+
+// rasterizeTile rasterizes the coverage of the path in the same region and
+// with the same arithmetic as the RenderPath method of the rasterizer.
+func rasterizeTile(path *canvas.Path, style canvas.Style, m canvas.Matrix, size image.Point, resolution canvas.Resolution) *tile {
+	fill := path
+	stroke := path
+	bounds := canvas.Rect{}
+	if style.HasFill() {
+		fill = path.Transform(m)
+		if !style.HasStroke() {
+			bounds = fill.Bounds()
+		}
+	}
+	if style.HasStroke() {
+		tolerance := canvas.PixelTolerance / resolution.DPMM()
+		if 0 < len(style.Dashes) {
+			stroke = stroke.Dash(style.DashOffset, style.Dashes...)
+		}
+		stroke = stroke.Stroke(style.StrokeWidth, style.StrokeCapper, style.StrokeJoiner, tolerance)
+		stroke = stroke.Transform(m)
+		bounds = stroke.Bounds()
+	}
+
+	padding := 2
+	dpmm := resolution.DPMM()
+	x := int(bounds.X*dpmm) - padding
+	y := size.Y - int((bounds.Y+bounds.H)*dpmm) - padding
+	w := int(bounds.W*dpmm) + 2*padding
+	h := int(bounds.H*dpmm) + 2*padding
+	if (x+w <= 0 || size.X <= x) && (y+h <= 0 || size.Y <= y) {
+		return &tile{} // outside of the image
+	}
+	x, y = max(x, 0), max(y, 0)
+	w, h = min(w, size.X-x), min(h, size.Y-y)
+	if w <= 0 || h <= 0 {
+		return &tile{}
+	}
+	t := &tile{rect: image.Rect(x, y, x+w, y+h)}
+	coverage := func(p *canvas.Path) *image.Alpha16 {
+		ras := imagevector.NewRasterizer(w, h)
+		p = p.Translate(-float64(x)/dpmm, -float64(size.Y-y-h)/dpmm)
+		p.ToRasterizer(ras, resolution)
+		mask := image.NewAlpha16(image.Rect(0, 0, w, h))
+		ras.Draw(mask, mask.Bounds(), image.Opaque, image.Point{})
+		return mask
+	}
+	if style.HasFill() {
+		t.fill = coverage(fill)
+	}
+	if style.HasStroke() {
+		t.stroke = coverage(stroke)
+	}
+	return t
+}
+
+// coverage returns the coverage of the fill of the tile at a pixel of the
+// image between 0 and 1.
+func (t *tile) coverage(x, y int) float64 {
+	if t.fill == nil || !(image.Point{x, y}).In(t.rect) {
+		return 0
+	}
+	return float64(t.fill.Alpha16At(x-t.rect.Min.X, y-t.rect.Min.Y).A) / 0xffff
+}
+
+// draw draws the fill and the stroke of the style through the coverage of
+// the tile over the image, which is in the blending color space, with the
+// same arithmetic as the rasterizer.
+func (t *tile) draw(img *image.RGBA, style canvas.Style, resolution canvas.Resolution, colorSpace canvas.ColorSpace) {
+	for _, part := range []struct {
+		mask  *image.Alpha16
+		paint canvas.Paint
+	}{{t.fill, style.Fill}, {t.stroke, style.Stroke}} {
+		if part.mask == nil {
+			continue
+		}
+		var src image.Image
+		if part.paint.IsColor() {
+			src = image.NewUniform(colorSpace.ToLinear(part.paint.Color))
+		} else if part.paint.IsGradient() {
+			gradient := part.paint.Gradient.SetColorSpace(colorSpace)
+			src = rasterizer.NewGradientImage(gradient, image.Point{}, img.Bounds().Size(), resolution)
+		} else {
+			continue
+		}
+		for y := t.rect.Min.Y; y < t.rect.Max.Y; y++ {
+			for x := t.rect.Min.X; x < t.rect.Max.X; x++ {
+				j := part.mask.PixOffset(x-t.rect.Min.X, y-t.rect.Min.Y)
+				ma := uint32(part.mask.Pix[j])<<8 | uint32(part.mask.Pix[j+1])
+				sr, sg, sb, sa := src.At(x, y).RGBA()
+				a := 0xffff - (sa * ma / 0xffff)
+				pix := img.Pix[img.PixOffset(x, y):]
+				pix[0] = uint8(((uint32(pix[0])*0x101*a + sr*ma) / 0xffff) >> 8)
+				pix[1] = uint8(((uint32(pix[1])*0x101*a + sg*ma) / 0xffff) >> 8)
+				pix[2] = uint8(((uint32(pix[2])*0x101*a + sb*ma) / 0xffff) >> 8)
+				pix[3] = uint8(((uint32(pix[3])*0x101*a + sa*ma) / 0xffff) >> 8)
+			}
+		}
+	}
+}
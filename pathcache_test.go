@@ -0,0 +1,96 @@
+package vectopng
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"testing"
+)
+
+// testBadge is a filled badge with a dashed stroke that recurs in the
+// drawables of testBatch.
+const testBadge = `<path android:pathData="M70,85 a15,15 0 1,1 30,0 a15,15 0 1,1 -30,0 z" android:fillColor="#ff5722" android:strokeColor="#80000000" android:strokeWidth="2" android:strokeLineCap="round" android:strokeDashArray="3,1"/>`
+
+// testBatch returns drawables that have the badge and a different square.
+func testBatch(n int) [][]byte {
+	batch := make([][]byte, n)
+	for i := range batch {
+		square := fmt.Sprintf(`<path android:pathData="M%d,10h40v40h-40z" android:fillColor="#2196f3"/>`, 10+i%40)
+		batch[i] = []byte(testVector(square + testBadge))
+	}
+	return batch
+}
+
+func TestPathCacheTiles(t *testing.T) {
+	tests := []struct {
+		name string
+		opts Options
+	}{
+		{name: "scale", opts: Options{Scale: 2.5}},
+		{name: "linear blend", opts: Options{LinearBlend: true}},
+		{name: "pixel snap", opts: Options{Scale: 1.3, SnapScale: true, PixelSnap: true}},
+		{name: "offset", opts: Options{OffsetX: 0.25, OffsetY: -30}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cached := test.opts
+			cached.PathCache = &PathCache{}
+			for i, xmlData := range testBatch(4) {
+				want := render(t, string(xmlData), test.opts).(*image.RGBA)
+				got := render(t, string(xmlData), cached).(*image.RGBA)
+				if !bytes.Equal(got.Pix, want.Pix) {
+					t.Errorf("drawable %d differs with the cached paths", i)
+				}
+			}
+			// The badge is rasterized for the first two drawables and taken
+			// from the cache for the others, while each square is new.
+			if hits := cached.PathCache.TileHits(); hits != 2 {
+				t.Errorf("got %d tile hits, want 2", hits)
+			}
+		})
+	}
+}
+
+func TestPathCacheTileKey(t *testing.T) {
+	opts := Options{PathCache: &PathCache{}}
+	for _, color := range []string{"#ff0000", "#00ff00", "#0000ff"} {
+		xmlData := testVector(`<path android:pathData="M10,10h80v80h-80z" android:fillColor="` + color + `"/>`)
+		img := render(t, xmlData, opts)
+		if got := fmt.Sprintf("%v", img.At(50, 50)); got != fmt.Sprintf("%v", render(t, xmlData, Options{}).At(50, 50)) {
+			t.Errorf("fill %s drawn as %s", color, got)
+		}
+	}
+	// The coverage is shared by paths of any color, but not by paths
+	// that differ in the slightest.
+	for _, pathData := range []string{"M10,10h80v80h-80z", "M10,10h80v80h-80.000001z"} {
+		render(t, testVector(`<path android:pathData="`+pathData+`" android:strokeColor="#000000" android:strokeWidth="1"/>`), opts)
+	}
+	if hits := opts.PathCache.TileHits(); hits != 1 {
+		t.Errorf("got %d tile hits, want 1", hits)
+	}
+}
+
+func BenchmarkConvert(b *testing.B) {
+	batch := testBatch(20)
+	for _, cachePaths := range []bool{false, true} {
+		name := "uncached"
+		if cachePaths {
+			name = "cache-paths"
+		}
+		b.Run(name, func(b *testing.B) {
+			opts := Options{Scale: 4}
+			if cachePaths {
+				opts.PathCache = &PathCache{}
+			}
+			for i := 0; i < b.N; i++ {
+				for _, xmlData := range batch {
+					c, err := Convert(xmlData, opts)
+					if err != nil {
+						b.Fatal(err)
+					}
+					Rasterize(c, opts)
+				}
+			}
+		})
+	}
+}
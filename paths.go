@@ -0,0 +1,107 @@
+package vectopng
+
+import (
+	"fmt"
+	"image/color"
+
+	"github.com/tdewolff/canvas"
+)
+
+// NormalizedPath is a path of a vector drawable as it would be drawn, see
+// NormalizedPaths.
+type NormalizedPath struct {
+	Name string `json:"name,omitempty"`
+	// PathData is the path in dp of the canvas with absolute commands.
+	PathData string `json:"pathData"`
+	// FillColor and StrokeColor are the resolved colors as #rrggbb with
+	// their alpha between 0 and 1, empty if the path is not filled or not
+	// stroked.
+	FillColor   string  `json:"fillColor,omitempty"`
+	FillAlpha   float64 `json:"fillAlpha,omitempty"`
+	StrokeColor string  `json:"strokeColor,omitempty"`
+	StrokeAlpha float64 `json:"strokeAlpha,omitempty"`
+	// FillGradient and StrokeGradient are the types of the gradients the
+	// path is filled or stroked with instead of a color.
+	FillGradient   string `json:"fillGradient,omitempty"`
+	StrokeGradient string `json:"strokeGradient,omitempty"`
+	// StrokeWidth is the stroke width in dp.
+	StrokeWidth float64 `json:"strokeWidth,omitempty"`
+	FillType    string  `json:"fillType,omitempty"`
+}
+
+// NormalizedPaths returns the paths of the vector drawable in document order
+// as they would be drawn by Convert, without rendering them. The path data
+// is transformed from the viewport to the size of the drawable, the colors
+// are resolved and tinted. Paths that would not be drawn are left out. Scale
+// and pixel size options have no effect.
+func NormalizedPaths(xmlData []byte, opts Options) ([]NormalizedPath, error) {
+	vec, err := parseVector(xmlData, opts)
+	if err != nil {
+		return nil, err
+	}
+	width, err := parseDimension(vec.Width, "width", opts.Density)
+	if err != nil {
+		return nil, err
+	}
+	height, err := parseDimension(vec.Height, "height", opts.Density)
+	if err != nil {
+		return nil, err
+	}
+	if opts.Width > 0 {
+		width = opts.Width
+	}
+	if opts.Height > 0 {
+		height = opts.Height
+	}
+
+	view := canvas.Identity.Scale(width/vec.ViewportWidth, height/vec.ViewportHeight)
+	if opts.RTL && vec.AutoMirrored {
+		view = view.Translate(vec.ViewportWidth, 0).Scale(-1, 1)
+	}
+	paths := []NormalizedPath{}
+	r := &renderer{opts: &opts, empty: true, vec: vec, groups: canvas.Identity, transform: view, paths: &paths, alpha: vec.Alpha}
+	r.tint, err = parseTint(vec, &opts, &r.stats)
+	if err != nil {
+		return nil, err
+	}
+	if err := r.drawNodes(vec.Children); err != nil {
+		return nil, err
+	}
+	r.warnUnknownNames(opts.Only)
+	r.warnUnknownNames(opts.Exclude)
+	if opts.Stats != nil {
+		*opts.Stats = r.stats
+	}
+	return paths, nil
+}
+
+// addPath adds a measured path to the normalized paths of the renderer.
+func (r *renderer) addPath(pathElem *vectorPath, path *canvas.Path, fillPaint canvas.Paint, strokePaint canvas.Paint, evenOdd bool) {
+	p := NormalizedPath{
+		Name:     pathElem.Name,
+		PathData: path.Transform(r.transform).ToSVG(),
+	}
+	if fillPaint.IsGradient() {
+		p.FillGradient = pathElem.gradient("fillColor").kind()
+	} else if fillPaint.Has() {
+		p.FillColor, p.FillAlpha = hexColor(fillPaint.Color)
+	}
+	if fillPaint.Has() && evenOdd {
+		p.FillType = "evenOdd"
+	}
+	if strokePaint.Has() && pathElem.StrokeWidth > 0 {
+		if strokePaint.IsGradient() {
+			p.StrokeGradient = pathElem.gradient("strokeColor").kind()
+		} else {
+			p.StrokeColor, p.StrokeAlpha = hexColor(strokePaint.Color)
+		}
+		p.StrokeWidth = pathElem.StrokeWidth * minScale(r.transform)
+	}
+	*r.paths = append(*r.paths, p)
+}
+
+// hexColor returns c as #rrggbb and its alpha between 0 and 1.
+func hexColor(c color.Color) (string, float64) {
+	n := color.NRGBAModel.Convert(c).(color.NRGBA)
+	return fmt.Sprintf("#%02x%02x%02x", n.R, n.G, n.B), float64(n.A) / 255
+}
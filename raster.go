@@ -0,0 +1,166 @@
+package vectopng
+
+import (
+	"image"
+	"image/draw"
+	"math"
+
+	"github.com/tdewolff/canvas"
+	"github.com/tdewolff/canvas/renderers/rasterizer"
+)
+
+// pixelRenderer is a rasterizer that optionally snaps the path coordinates
+// to the pixel grid, draws the paths without anti-aliasing, stretches the
+// canvas to the rounded size of the image, reuses rasterized paths and
+// applies the tint of vectors as a color filter.
+type pixelRenderer struct {
+	*rasterizer.Rasterizer
+	img         *image.RGBA
+	resolution  canvas.Resolution
+	colorSpace  canvas.ColorSpace
+	noAntialias bool
+	pixelSnap   bool
+	// tiles reuses the rasterized paths of earlier images if not nil.
+	tiles *PathCache
+	// under holds the images that the layers of tinted vectors are drawn
+	// over once they are filtered.
+	under []*image.RGBA
+	// stretch scales the canvas to fill the rounded image size if not nil.
+	stretch *canvas.Matrix
+}
+
+func (r *pixelRenderer) RenderPath(path *canvas.Path, style canvas.Style, m canvas.Matrix) {
+	if style.FillRule == canvas.EvenOdd && style.HasFill() {
+		// The rasterizer only fills with the nonZero rule, so an equivalent
+		// outline is filled, while the stroke still follows all subpaths of
+		// the original path.
+		fillStyle := style
+		fillStyle.FillRule = canvas.NonZero
+		fillStyle.Stroke = canvas.Paint{Color: canvas.Transparent}
+		r.RenderPath(evenOddPath(path), fillStyle, m)
+		if style.HasStroke() {
+			strokeStyle := style
+			strokeStyle.Fill = canvas.Paint{Color: canvas.Transparent}
+			r.RenderPath(path, strokeStyle, m)
+		}
+		return
+	}
+	if r.stretch != nil {
+		m = r.stretch.Mul(m)
+		if style.Fill.IsGradient() {
+			style.Fill.Gradient = style.Fill.Gradient.SetView(*r.stretch)
+		}
+		if style.Stroke.IsGradient() {
+			style.Stroke.Gradient = style.Stroke.Gradient.SetView(*r.stretch)
+		}
+	}
+	if filter, ok := style.Fill.Gradient.(*tintFilter); ok {
+		r.renderFilter(filter, path, m)
+		return
+	}
+	if r.pixelSnap {
+		path = snapPath(path, m, r.resolution.DPMM())
+	}
+	if !r.noAntialias && r.tiles != nil && !style.Fill.IsPattern() && !style.Stroke.IsPattern() {
+		size := r.img.Bounds().Size()
+		r.tiles.tile(path, style, m, size, r.resolution).draw(r.img, style, r.resolution, r.colorSpace)
+		return
+	} else if !r.noAntialias {
+		r.Rasterizer.RenderPath(path, style, m)
+		return
+	}
+
+	if style.HasFill() {
+		fillStyle := style
+		fillStyle.Stroke = canvas.Paint{Color: canvas.Transparent}
+		r.renderAliased(path, fillStyle, style.Fill, m)
+	}
+	if style.HasStroke() {
+		strokeStyle := style
+		strokeStyle.Fill = canvas.Paint{Color: canvas.Transparent}
+		r.renderAliased(path, strokeStyle, style.Stroke, m)
+	}
+}
+
+// renderFilter starts a new layer at the start of a tinted vector and
+// composites the filtered layer over the image below at its end.
+func (r *pixelRenderer) renderFilter(filter *tintFilter, area *canvas.Path, m canvas.Matrix) {
+	if !filter.end {
+		r.under = append(r.under, r.img)
+		r.img = image.NewRGBA(r.img.Bounds())
+		r.Rasterizer = rasterizer.FromImage(r.img, r.resolution, r.colorSpace)
+		return
+	}
+	layer := r.img
+	r.img, r.under = r.under[len(r.under)-1], r.under[:len(r.under)-1]
+	r.Rasterizer = rasterizer.FromImage(r.img, r.resolution, r.colorSpace)
+	style := canvas.Style{Fill: canvas.Paint{Color: canvas.Black}}
+	filter.draw(r.img, layer, rasterizeTile(area, style, m, r.img.Bounds().Size(), r.resolution), r.colorSpace)
+}
+
+// renderAliased draws the path with only the fill or only the stroke of the
+// style set. The coverage of the path is rendered as mask, which is reduced
+// to fully covered and uncovered pixels before the paint is drawn through it.
+func (r *pixelRenderer) renderAliased(path *canvas.Path, style canvas.Style, paint canvas.Paint, m canvas.Matrix) {
+	bounds := r.img.Bounds()
+	black := canvas.Paint{Color: canvas.Black}
+	if style.HasFill() {
+		style.Fill = black
+	} else {
+		style.Stroke = black
+	}
+	coverage := image.NewRGBA(bounds)
+	rasterizer.FromImage(coverage, r.resolution, canvas.LinearColorSpace{}).RenderPath(path, style, m)
+
+	mask := image.NewAlpha(bounds)
+	for i := range mask.Pix {
+		if coverage.Pix[4*i+3] >= 0x80 {
+			mask.Pix[i] = 0xff
+		}
+	}
+
+	// The paint is rendered to the whole image so that gradients keep their
+	// position. It is left in the blending color space like the image, which
+	// the rasterizer converts back when closed.
+	src := image.NewRGBA(bounds)
+	w, h := r.Size()
+	rasterizer.FromImage(src, r.resolution, r.colorSpace).RenderPath(canvas.Rectangle(w, h),
+		canvas.Style{Fill: paint, Stroke: canvas.Paint{Color: canvas.Transparent}}, canvas.Identity)
+	draw.DrawMask(r.img, bounds, src, bounds.Min, mask, bounds.Min, draw.Over)
+}
+
+// snapPath rounds the points of the path to the pixel grid of the image that
+// the matrix m and dpmm map the path to.
+func snapPath(path *canvas.Path, m canvas.Matrix, dpmm float64) *canvas.Path {
+	toPixels := canvas.Identity.Scale(dpmm, dpmm).Mul(m)
+	fromPixels := toPixels.Inv()
+	snap := func(p canvas.Point) (float64, float64) {
+		p = toPixels.Dot(p)
+		p = fromPixels.Dot(canvas.Point{X: math.Round(p.X), Y: math.Round(p.Y)})
+		return p.X, p.Y
+	}
+
+	snapped := &canvas.Path{}
+	for scanner := path.Scanner(); scanner.Scan(); {
+		x, y := snap(scanner.End())
+		switch scanner.Cmd() {
+		case canvas.MoveToCmd:
+			snapped.MoveTo(x, y)
+		case canvas.LineToCmd:
+			snapped.LineTo(x, y)
+		case canvas.QuadToCmd:
+			cpx, cpy := snap(scanner.CP1())
+			snapped.QuadTo(cpx, cpy, x, y)
+		case canvas.CubeToCmd:
+			cpx1, cpy1 := snap(scanner.CP1())
+			cpx2, cpy2 := snap(scanner.CP2())
+			snapped.CubeTo(cpx1, cpy1, cpx2, cpy2, x, y)
+		case canvas.ArcToCmd:
+			rx, ry, rot, large, sweep := scanner.Arc()
+			snapped.ArcTo(rx, ry, rot, large, sweep, x, y)
+		case canvas.CloseCmd:
+			snapped.Close()
+		}
+	}
+	return snapped
+}
@@ -0,0 +1,836 @@
+package vectopng
+
+import (
+	"errors"
+	"fmt"
+	"image/color"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/tdewolff/canvas"
+)
+
+// renderer draws the elements of a vector drawable to a canvas context.
+type renderer struct {
+	ctx  *canvas.Context
+	opts *Options
+	tint *vectorTint
+	// filter applies the tint of vec to its rendered paths if not nil, the
+	// colors of the paths are then left untinted.
+	filter *tintFilter
+	stats  Stats
+	// bounds is the union of the bounds of all drawn paths in canvas
+	// coordinates, empty is set until the first path is drawn.
+	bounds canvas.Rect
+	empty  bool
+	// clip is the area in canvas coordinates, or in the coordinates of
+	// transform without context, that paths are clipped to if not nil.
+	clip *canvas.Path
+	// pathIndex is the document order index of the next path for error
+	// messages.
+	pathIndex int
+	// pathNames are the distinct names of the paths in document order.
+	pathNames []string
+	// vec is the vector whose nodes are drawn, which is a referenced
+	// drawable while drawDrawable draws it.
+	vec *vector
+	// drawables are the names of the referenced drawables being drawn.
+	drawables []string
+	// transform maps the coordinates of the drawn nodes to the viewport of
+	// the vector given to Bounds, or to dp for NormalizedPaths, where no
+	// context holds a view.
+	transform canvas.Matrix
+	// groups is the transformation of the groups around the drawn nodes,
+	// which maps them to the viewport of vec.
+	groups canvas.Matrix
+	// paths collects the measured paths for NormalizedPaths if not nil.
+	paths *[]NormalizedPath
+	// alpha is the android:alpha of vec multiplied by those of the vectors
+	// referencing it, except for that of a vector with a filter, which the
+	// filter applies.
+	alpha float64
+}
+
+// renderVectors renders the vectors as layers onto one canvas in the given
+// order. The size of the canvas follows from the first vector, the viewports
+// of the others are scaled to the same size.
+func renderVectors(vecs []*vector, opts Options) (*canvas.Canvas, error) {
+	originalWidth, err := parseDimension(vecs[0].Width, "width", opts.Density)
+	if err != nil {
+		return nil, err
+	}
+
+	originalHeight, err := parseDimension(vecs[0].Height, "height", opts.Density)
+	if err != nil {
+		return nil, err
+	}
+	for i, vec := range vecs[1:] {
+		// The other sizes are only checked, the drawings are scaled to the
+		// size of the first vector.
+		if _, err := parseDimension(vec.Width, "width", opts.Density); err != nil {
+			return nil, layerError(err, i+1, len(vecs))
+		}
+		if _, err := parseDimension(vec.Height, "height", opts.Density); err != nil {
+			return nil, layerError(err, i+1, len(vecs))
+		}
+	}
+
+	if opts.Viewport != (canvas.Rect{}) && (opts.Viewport.W <= 0 || opts.Viewport.H <= 0) {
+		return nil, fmt.Errorf("viewport region %gx%g must have a positive size", opts.Viewport.W, opts.Viewport.H)
+	}
+
+	width := originalWidth
+	if opts.Width > 0 {
+		width = opts.Width
+	}
+
+	height := originalHeight
+	if opts.Height > 0 {
+		height = opts.Height
+	}
+
+	drawingWidth := originalWidth
+	drawingHeight := originalHeight
+	if opts.PixelWidth > 0 && opts.PixelHeight > 0 {
+		// Stretch the canvas vertically to the aspect ratio of the
+		// requested pixel size, the scale factor follows from the width.
+		// A padding in pixels is left out of the drawing.
+		pixelWidth, pixelHeight := float64(opts.PixelWidth), float64(opts.PixelHeight)
+		if !opts.PaddingPercent && pixelWidth > 2*opts.Padding && pixelHeight > 2*opts.Padding {
+			pixelWidth -= 2 * opts.Padding
+			pixelHeight -= 2 * opts.Padding
+		}
+		stretch := pixelHeight / pixelWidth * width / height
+		height *= stretch
+		drawingHeight *= stretch
+	} else if opts.PixelWidth <= 0 && opts.PixelHeight <= 0 && opts.ScaleY > 0 {
+		// The same for a separate vertical scale factor.
+		stretch := opts.ScaleY / opts.scaleFactor(&canvas.Canvas{W: width, H: height})
+		height *= stretch
+		drawingHeight *= stretch
+	}
+
+	r := renderer{opts: &opts, stats: Stats{Width: width, Height: height}, empty: true, groups: canvas.Identity}
+	c := canvas.New(width, height)
+	r.ctx = canvas.NewContext(c)
+	r.ctx.SetCoordSystem(canvas.CartesianIV)
+	var crop canvas.Rect
+	if opts.AdaptiveMask != "" {
+		crop = maskRect(width, height)
+		r.clip = opts.AdaptiveMask.path(crop)
+	}
+
+	for i, vec := range vecs {
+		r.vec = vec
+		r.alpha = vec.Alpha
+		r.pathIndex = 0
+		// The clip-paths at the top of a vector do not clip the next layers.
+		clip := r.clip
+		r.tint, err = parseTint(vec, &opts, &r.stats)
+		if err != nil {
+			return nil, layerError(err, i, len(vecs))
+		}
+
+		region := canvas.Rect{W: vec.ViewportWidth, H: vec.ViewportHeight}
+		if opts.Viewport != (canvas.Rect{}) {
+			// The region is given in the viewport of the first vector.
+			sx := vec.ViewportWidth / vecs[0].ViewportWidth
+			sy := vec.ViewportHeight / vecs[0].ViewportHeight
+			region = canvas.Rect{X: opts.Viewport.X * sx, Y: opts.Viewport.Y * sy, W: opts.Viewport.W * sx, H: opts.Viewport.H * sy}
+		}
+		view := canvas.Identity
+		if opts.PreserveAspect {
+			scale := math.Min(drawingWidth/region.W, drawingHeight/region.H)
+			marginX := (drawingWidth - region.W*scale) / 2
+			marginY := (drawingHeight - region.H*scale) / 2
+			view = view.Translate(marginX, marginY).Scale(scale, scale)
+		} else {
+			view = view.Scale(drawingWidth/region.W, drawingHeight/region.H)
+		}
+		view = view.Translate(-region.X, -region.Y)
+		if opts.RTL && vec.AutoMirrored {
+			view = view.Translate(vec.ViewportWidth, 0).Scale(-1, 1)
+		}
+
+		// The paths of a tinted vector are drawn without the tint, the
+		// opacity and the alpha of the vector, which the filter applies to
+		// all of them at once.
+		r.filter = nil
+		if r.tint != nil {
+			r.filter = &tintFilter{tint: r.tint, alpha: opts.opacity() * vec.Alpha}
+			r.alpha = 1
+			r.fillCanvas(canvas.Paint{Gradient: r.filter})
+		}
+		r.ctx.SetView(view)
+
+		if err := r.drawNodes(vec.Children); err != nil {
+			return nil, layerError(err, i, len(vecs))
+		}
+
+		r.clip = clip
+
+		if r.filter != nil {
+			r.fillCanvas(canvas.Paint{Gradient: &tintFilter{tint: r.tint, alpha: r.filter.alpha, end: true}})
+		}
+	}
+
+	r.warnUnknownNames(opts.Only)
+	r.warnUnknownNames(opts.Exclude)
+
+	// The canvas is cropped to the adaptive icon or trimmed first, then the
+	// padding is added around it and the background is drawn below all
+	// paths, so that it fills the padding as well.
+	if opts.AdaptiveMask != "" {
+		r.clipCanvas(c, crop)
+	} else if opts.Trim && !r.empty {
+		r.clipCanvas(c, r.bounds)
+	}
+	if opts.Padding > 0 {
+		padX, padY, err := opts.padding(c)
+		if err != nil {
+			return nil, err
+		}
+		r.clipCanvas(c, canvas.Rect{X: -padX, Y: -padY, W: c.W + 2*padX, H: c.H + 2*padY})
+	}
+	if opts.Background != nil {
+		r.ctx.SetZIndex(-1)
+		r.fillCanvas(canvas.Paint{Color: color.RGBAModel.Convert(opts.Background).(color.RGBA)})
+		r.ctx.SetZIndex(0)
+	}
+
+	if opts.Stats != nil {
+		*opts.Stats = r.stats
+	}
+	return c, nil
+}
+
+// clipCanvas crops the canvas to rect, which may also enlarge it, and moves
+// the clip area along.
+func (r *renderer) clipCanvas(c *canvas.Canvas, rect canvas.Rect) {
+	c.Clip(rect)
+	if r.clip != nil {
+		r.clip = r.clip.Translate(-rect.X, -rect.Y)
+	}
+	r.stats.Width = c.W
+	r.stats.Height = c.H
+}
+
+// padding returns the horizontal and vertical padding of the Padding option
+// in canvas units for the canvas c. Pixels are converted with the scale the
+// image is saved at: an image of a given pixel width or height keeps it, so
+// the drawing is scaled to the pixels left inside the padding. The padding
+// of the sides whose pixel size is not given is snapped, so that the padded
+// canvas is a whole number of pixels.
+func (opts *Options) padding(c *canvas.Canvas) (float64, float64, error) {
+	scale := opts.scaleFactor(c)
+	if opts.PaddingPercent {
+		padding := opts.Padding / 100 * math.Max(c.W, c.H)
+		if opts.PixelWidth > 0 {
+			scale = float64(opts.PixelWidth) / (c.W + 2*padding)
+		} else if opts.PixelHeight > 0 {
+			scale = float64(opts.PixelHeight) / (c.H + 2*padding)
+		}
+		padX, padY := opts.snapPadding(c, padding, scale)
+		return padX, padY, nil
+	}
+
+	if opts.PixelWidth > 0 {
+		scale = (float64(opts.PixelWidth) - 2*opts.Padding) / c.W
+	} else if opts.PixelHeight > 0 {
+		scale = (float64(opts.PixelHeight) - 2*opts.Padding) / c.H
+	}
+	if scale <= 0 {
+		return 0, 0, fmt.Errorf("padding %g must be less than half the pixel width or height of the image", opts.Padding)
+	}
+	padX, padY := opts.snapPadding(c, opts.Padding/scale, scale)
+	return padX, padY, nil
+}
+
+// snapPadding returns the padding on both sides of the canvas c, widened or
+// narrowed so that the padded canvas is a whole number of pixels at the
+// given scale. Otherwise the pixels at the edge of the image are only partly
+// covered by the background. A given pixel width or height is kept.
+func (opts *Options) snapPadding(c *canvas.Canvas, padding float64, scale float64) (float64, float64) {
+	snap := func(size float64) float64 {
+		pixels := math.Round((size + 2*padding) * scale)
+		if pixels < size*scale {
+			pixels++
+		}
+		return (pixels/scale - size) / 2
+	}
+	padX, padY := padding, padding
+	if opts.PixelWidth <= 0 {
+		padX = snap(c.W)
+	}
+	if opts.PixelHeight <= 0 {
+		padY = snap(c.H)
+	}
+	return padX, padY
+}
+
+// layerError adds the index of the layer to err if there are several.
+func layerError(err error, i int, layers int) error {
+	if layers > 1 {
+		return fmt.Errorf("layer %d: %w", i, err)
+	}
+	return err
+}
+
+// drawNodes draws the nodes in document order.
+func (r *renderer) drawNodes(nodes []vectorNode) error {
+	for _, node := range nodes {
+		if node.Path != nil {
+			if err := r.drawPath(node.Path); err != nil {
+				return err
+			}
+		} else if node.Group != nil {
+			if err := r.drawGroup(node.Group); err != nil {
+				return err
+			}
+		} else if node.ClipPath != nil {
+			if err := r.addClip(node.ClipPath); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// drawGroup draws the children of the group with its transformation. The
+// clip-paths of the group do not clip the nodes after it.
+func (r *renderer) drawGroup(group *vectorGroup) error {
+	m := group.matrix()
+	groups, clip := r.groups, r.clip
+	r.groups = groups.Mul(m)
+	defer func() { r.groups, r.clip = groups, clip }()
+	if r.ctx != nil {
+		view := r.ctx.View()
+		r.ctx.SetView(view.Mul(m))
+		defer r.ctx.SetView(view)
+	} else {
+		transform := r.transform
+		r.transform = transform.Mul(m)
+		defer func() { r.transform = transform }()
+	}
+	return r.drawNodes(group.Children)
+}
+
+func (r *renderer) drawPath(pathElem *vectorPath) error {
+	i := r.pathIndex
+	r.pathIndex++
+	if pathElem.Name != "" && !slices.Contains(r.pathNames, pathElem.Name) {
+		r.pathNames = append(r.pathNames, pathElem.Name)
+	}
+	if !r.selected(pathElem.Name) {
+		return nil
+	}
+	if pathElem.Drawable != "" {
+		return r.drawDrawable(pathElem, i)
+	}
+
+	pathData, err := resolveString(pathElem.PathData, r.opts.Strings)
+	if err != nil {
+		return fmt.Errorf("cannot resolve pathData of path %d: %w", i, err)
+	}
+	normalized, stripped := normalizePathData(pathData)
+	path, err := r.opts.parsePath(normalized)
+	if err != nil && normalized != pathData {
+		return fmt.Errorf("invalid pathData \"%s\" (normalized \"%s\") of path %d: %w", snippet(pathData, 32), snippet(normalized, 32), i, err)
+	} else if err != nil {
+		return fmt.Errorf("invalid pathData \"%s\" of path %d: %w", snippet(pathData, 32), i, err)
+	}
+	if stripped != "" && r.opts.Warn != nil {
+		r.opts.Warn(fmt.Sprintf("path %d: ignored the characters \"%s\" in pathData", i, stripped))
+	}
+	path = trimPath(path, pathElem.TrimPathStart, pathElem.TrimPathEnd, pathElem.TrimPathOffset)
+
+	evenOdd, err := parseFillType(pathElem.FillType)
+	if err != nil {
+		return fmt.Errorf("invalid fillType \"%s\" of path %d: %w", pathElem.FillType, i, err)
+	}
+	dashes, err := parseDashArray(pathElem.StrokeDashArray)
+	if err != nil {
+		return fmt.Errorf("invalid strokeDashArray \"%s\" of path %d: %w", pathElem.StrokeDashArray, i, err)
+	}
+	capper, err := parseLineCap(pathElem.StrokeLineCap)
+	if err != nil {
+		return fmt.Errorf("invalid strokeLineCap \"%s\" of path %d: %w", pathElem.StrokeLineCap, i, err)
+	}
+	joiner, err := parseLineJoin(pathElem.StrokeLineJoin, pathElem.StrokeMiterLimit)
+	if err != nil {
+		return fmt.Errorf("invalid strokeLineJoin \"%s\" of path %d: %w", pathElem.StrokeLineJoin, i, err)
+	}
+
+	fillPaint, err := r.paint(pathElem, "fillColor", pathElem.FillColor, "fillAlpha", pathElem.FillAlpha, i)
+	if err != nil {
+		return err
+	}
+	strokePaint, err := r.paint(pathElem, "strokeColor", pathElem.StrokeColor, "strokeAlpha", pathElem.StrokeAlpha, i)
+	if err != nil {
+		return err
+	}
+
+	// Like on Android, a path is only stroked if the stroke width is greater
+	// than zero, which is the default.
+	if (pathElem.StrokeColor != "" || strokePaint.IsGradient()) && pathElem.StrokeWidth <= 0 && r.opts.Warn != nil {
+		r.opts.Warn(fmt.Sprintf("path %d has a strokeColor but no strokeWidth, the stroke is not drawn", i))
+	}
+	filled := fillPaint.Has()
+	stroked := strokePaint.Has() && pathElem.StrokeWidth > 0
+	if path.Empty() || (!filled && !stroked) {
+		r.stats.PathsSkipped++
+		return nil
+	}
+	style := canvas.DefaultStyle
+	style.StrokeWidth = pathElem.StrokeWidth
+	style.DashOffset = pathElem.StrokeDashOffset
+	style.Dashes = dashes
+	style.StrokeCapper = capper
+	style.StrokeJoiner = joiner
+	if r.opts.CheckBounds {
+		if err := r.checkBounds(pathElem, path, i, style, filled, stroked); err != nil {
+			return err
+		}
+	}
+	if r.ctx == nil {
+		// Bounds only measures the paths in viewport coordinates.
+		r.addBounds(path, r.transform, style, filled, stroked)
+		if r.paths != nil {
+			r.addPath(pathElem, path, fillPaint, strokePaint, evenOdd)
+		}
+		r.stats.PathsDrawn++
+		return nil
+	}
+	// Gradients are given in viewport coordinates, but the context draws
+	// them in canvas coordinates.
+	paintView := canvas.Identity.Translate(r.opts.OffsetX, r.opts.OffsetY).Mul(r.ctx.View())
+	if fillPaint.IsGradient() {
+		fillPaint.Gradient = fillPaint.Gradient.SetView(paintView)
+	}
+	if strokePaint.IsGradient() {
+		strokePaint.Gradient = strokePaint.Gradient.SetView(paintView)
+	}
+	strokeWidth := pathElem.StrokeWidth
+	dashOffset := pathElem.StrokeDashOffset
+	if r.opts.UniformStroke && stroked {
+		// The path is stroked in canvas coordinates, where the view no
+		// longer stretches the stroke.
+		view := r.ctx.View()
+		scale := minScale(view)
+		path = path.Transform(view)
+		strokeWidth *= scale
+		dashOffset *= scale
+		for j := range dashes {
+			dashes[j] *= scale
+		}
+		r.ctx.SetView(canvas.Identity)
+		defer r.ctx.SetView(view)
+	}
+	// The evenOdd rule is kept in the style, so that SVG images declare it
+	// and pixelRenderer fills an equivalent outline. Clipping needs that
+	// outline right away.
+	fill := path
+	fillRule := canvas.NonZero
+	if evenOdd && filled && r.clip != nil {
+		fill = evenOddPath(path)
+	} else if evenOdd {
+		fillRule = canvas.EvenOdd
+	}
+	r.ctx.SetFillRule(fillRule)
+	r.ctx.SetFill(fillPaint)
+	r.ctx.SetStroke(strokePaint)
+	r.ctx.SetStrokeWidth(strokeWidth)
+	r.ctx.SetDashes(dashOffset, dashes...)
+	r.ctx.SetStrokeCapper(capper)
+	r.ctx.SetStrokeJoiner(joiner)
+	if r.clip != nil {
+		r.drawClipped(fill, path, fillPaint, strokePaint)
+	} else {
+		r.ctx.DrawPath(r.opts.OffsetX, r.opts.OffsetY, path)
+	}
+	r.stats.PathsDrawn++
+	if r.opts.Trim {
+		r.addBounds(path, r.pathMatrix(), r.ctx.Style, filled, stroked)
+	}
+	return nil
+}
+
+// paint returns the paint of a color attribute of the path, which is the
+// gradient given as <aapt:attr> or else the color value, with the alpha of
+// the alpha attribute multiplied in. It is transparent if neither is given.
+func (r *renderer) paint(pathElem *vectorPath, attr string, value string, alphaAttr string, alpha float64, i int) (canvas.Paint, error) {
+	if math.IsNaN(alpha) || alpha < 0 || alpha > 1 {
+		return canvas.Paint{}, fmt.Errorf("%s %g of path %d is outside of 0 to 1", alphaAttr, alpha, i)
+	}
+	if g := pathElem.gradient(attr); g != nil {
+		gradient, err := r.gradient(g, alpha)
+		if err != nil {
+			return canvas.Paint{}, fmt.Errorf("invalid gradient of the %s of path %d: %w", attr, i, err)
+		}
+		return canvas.Paint{Gradient: gradient}, nil
+	}
+	if value == "" {
+		return canvas.Paint{Color: canvas.Transparent}, nil
+	}
+	c, err := r.opts.color(value, &r.stats)
+	if err != nil {
+		return canvas.Paint{}, err
+	}
+	c, _ = scaleAlpha(c, alpha)
+	return canvas.Paint{Color: color.RGBAModel.Convert(r.tintColor(c)).(color.RGBA)}, nil
+}
+
+// addClip intersects the clip area with the path of the clip-path like
+// Android, which clips the following nodes of the group to all clip-paths
+// before them.
+func (r *renderer) addClip(clipElem *vectorClipPath) error {
+	pathData, err := resolveString(clipElem.PathData, r.opts.Strings)
+	if err != nil {
+		return fmt.Errorf("cannot resolve pathData of the clip-path before path %d: %w", r.pathIndex, err)
+	}
+	normalized, _ := normalizePathData(pathData)
+	path, err := r.opts.parsePath(normalized)
+	if err != nil {
+		return fmt.Errorf("invalid pathData \"%s\" of the clip-path before path %d: %w", snippet(pathData, 32), r.pathIndex, err)
+	}
+	evenOdd, err := parseFillType(clipElem.FillType)
+	if err != nil {
+		return fmt.Errorf("invalid fillType \"%s\" of the clip-path before path %d: %w", clipElem.FillType, r.pathIndex, err)
+	}
+	if evenOdd {
+		path = evenOddPath(path)
+	}
+
+	m := r.transform
+	if r.ctx != nil {
+		m = r.pathMatrix()
+	}
+	clip := path.Transform(m)
+	if r.clip != nil {
+		clip = intersect(r.clip, clip)
+	}
+	r.clip = clip
+	return nil
+}
+
+// selected reports whether the path with the given name is drawn with the
+// Only and Exclude options.
+func (r *renderer) selected(name string) bool {
+	if len(r.opts.Only) > 0 && !slices.Contains(r.opts.Only, name) {
+		return false
+	}
+	return !slices.Contains(r.opts.Exclude, name)
+}
+
+// warnUnknownNames warns about the names that no path has.
+func (r *renderer) warnUnknownNames(names []string) {
+	if r.opts.Warn == nil {
+		return
+	}
+	for _, name := range names {
+		if !slices.Contains(r.pathNames, name) {
+			available := "none"
+			if len(r.pathNames) > 0 {
+				available = strings.Join(r.pathNames, ", ")
+			}
+			r.opts.Warn(fmt.Sprintf("unknown path name \"%s\", available names: %s", name, available))
+		}
+	}
+}
+
+// drawClipped draws the intersections of the fill and the stroke outline of
+// the path with the clip area. The fill differs from the path for the
+// evenOdd rule. The style of the context is restored afterwards.
+func (r *renderer) drawClipped(fill *canvas.Path, path *canvas.Path, fillPaint canvas.Paint, strokePaint canvas.Paint) {
+	clip := r.clip.Transform(r.pathMatrix().Inv())
+	style := r.ctx.Style
+	r.ctx.SetStrokeColor(canvas.Transparent)
+	if fillPaint.Has() {
+		r.ctx.DrawPath(r.opts.OffsetX, r.opts.OffsetY, intersect(fill, clip))
+	}
+	if strokePaint.Has() && style.StrokeWidth > 0 {
+		outline := strokeOutline(path, style)
+		r.ctx.SetFill(strokePaint)
+		r.ctx.DrawPath(r.opts.OffsetX, r.opts.OffsetY, intersect(outline, clip))
+	}
+	r.ctx.Style = style
+}
+
+// parseFillType reports whether the fillType attribute selects the evenOdd
+// rule. The default is nonZero.
+func parseFillType(s string) (evenOdd bool, err error) {
+	switch s {
+	case "", "nonZero":
+		return false, nil
+	case "evenOdd":
+		return true, nil
+	}
+	return false, errors.New("expected nonZero or evenOdd")
+}
+
+// parseLineCap parses the strokeLineCap attribute, which defaults to butt.
+func parseLineCap(s string) (canvas.Capper, error) {
+	switch s {
+	case "", "butt":
+		return canvas.ButtCap, nil
+	case "round":
+		return canvas.RoundCap, nil
+	case "square":
+		return canvas.SquareCap, nil
+	}
+	return nil, errors.New("expected butt, round or square")
+}
+
+// parseLineJoin parses the strokeLineJoin attribute, which defaults to
+// miter. Like on Android, a miter join longer than miterLimit times the
+// stroke width is beveled.
+func parseLineJoin(s string, miterLimit float64) (canvas.Joiner, error) {
+	switch s {
+	case "", "miter":
+		return canvas.MiterClipJoin(canvas.BevelJoin, miterLimit), nil
+	case "round":
+		return roundJoiner{}, nil
+	case "bevel":
+		return canvas.BevelJoin, nil
+	}
+	return nil, errors.New("expected miter, round or bevel")
+}
+
+// roundJoiner joins the segments of a stroke like canvas.RoundJoin, but with
+// cubic Béziers instead of an arc. The canvas version in use mistakes any
+// arc whose end points are level for a half circle, which bulges out the
+// round join of every symmetric corner.
+type roundJoiner struct{}
+
+func (roundJoiner) Join(rhs, lhs *canvas.Path, halfWidth float64, pivot, n0, n1 canvas.Point, r0, r1 float64) {
+	rEnd := pivot.Add(n1)
+	lEnd := pivot.Sub(n1)
+	if n0.Rot90CW().Dot(n1) >= 0 {
+		// The stroke bends clockwise, the join is on the left.
+		rhs.LineTo(rEnd.X, rEnd.Y)
+		arcCubes(lhs, pivot, n0.Neg(), n1.Neg(), false)
+	} else {
+		arcCubes(rhs, pivot, n0, n1, true)
+		lhs.LineTo(lEnd.X, lEnd.Y)
+	}
+}
+
+func (roundJoiner) String() string {
+	return "Round"
+}
+
+// arcCubes adds the arc around the center from center+from to center+to,
+// which have the same length, as cubic Béziers of at most a quarter circle
+// each. The arc runs counter clockwise if ccw is set.
+func arcCubes(p *canvas.Path, center canvas.Point, from canvas.Point, to canvas.Point, ccw bool) {
+	radius := from.Length()
+	start := from.Angle()
+	delta := to.Angle() - start
+	if ccw && delta < 0 {
+		delta += 2 * math.Pi
+	} else if !ccw && delta > 0 {
+		delta -= 2 * math.Pi
+	}
+	n := math.Max(1, math.Ceil(math.Abs(delta)/(math.Pi/2)))
+	step := delta / n
+	k := 4.0 / 3.0 * math.Tan(step/4) * radius
+	for i := 0.0; i < n; i++ {
+		a0, a1 := start+i*step, start+(i+1)*step
+		sin0, cos0 := math.Sincos(a0)
+		sin1, cos1 := math.Sincos(a1)
+		p.CubeTo(center.X+radius*cos0-k*sin0, center.Y+radius*sin0+k*cos0,
+			center.X+radius*cos1+k*sin1, center.Y+radius*sin1-k*cos1,
+			center.X+radius*cos1, center.Y+radius*sin1)
+	}
+}
+
+// strokeOutline returns the area covered by the stroke of the path with the
+// given style, including its dashes.
+func strokeOutline(path *canvas.Path, style canvas.Style) *canvas.Path {
+	if style.IsDashed() {
+		path = path.Dash(style.DashOffset, style.Dashes...)
+	}
+	return path.Stroke(style.StrokeWidth, style.StrokeCapper, style.StrokeJoiner, canvas.Tolerance)
+}
+
+// normalizePathData cleans up path data pasted from other sources. Unicode
+// spaces and separators become plain spaces and are collapsed, Unicode
+// minus signs and dashes and full-width commas are replaced by their ASCII
+// forms. Characters that cannot occur in path data are removed and returned
+// as stripped, the parser handles missing separators between numbers and
+// commands itself.
+func normalizePathData(s string) (normalized string, stripped string) {
+	var b, removed strings.Builder
+	space := false
+	for _, c := range s {
+		switch {
+		case unicode.IsSpace(c) || c == '\u200b' || c == '\ufeff':
+			space = true
+			continue
+		case c == '\u2212' || c == '\u2013' || c == '\u2010' || c == '\ufe63' || c == '\uff0d':
+			c = '-'
+		case c == '\uff0c':
+			c = ','
+		case !strings.ContainsRune("MmLlHhVvCcSsQqTtAaZz0123456789.,+-eE", c):
+			removed.WriteRune(c)
+			continue
+		}
+		if space && b.Len() > 0 {
+			b.WriteByte(' ')
+		}
+		space = false
+		b.WriteRune(c)
+	}
+	return b.String(), removed.String()
+}
+
+// parseDashArray parses a comma or space separated list of dash and gap
+// lengths. Less than two lengths result in a solid stroke, which is returned
+// as nil.
+func parseDashArray(s string) ([]float64, error) {
+	fields := strings.FieldsFunc(s, func(r rune) bool {
+		return r == ',' || unicode.IsSpace(r)
+	})
+	dashes := make([]float64, len(fields))
+	for i, field := range fields {
+		d, err := strconv.ParseFloat(field, 64)
+		if err != nil {
+			return nil, err
+		} else if d < 0 {
+			return nil, fmt.Errorf("negative length %g", d)
+		}
+		dashes[i] = d
+	}
+	if len(dashes) < 2 {
+		return nil, nil
+	}
+	return dashes, nil
+}
+
+// minScale returns the smaller of the horizontal and vertical scale factors
+// of the matrix.
+func minScale(m canvas.Matrix) float64 {
+	return math.Min(math.Hypot(m[0][0], m[1][0]), math.Hypot(m[0][1], m[1][1]))
+}
+
+// pathMatrix returns the transformation of the path coordinates to canvas
+// coordinates, the same as applied by DrawPath for the CartesianIV
+// coordinate system.
+func (r *renderer) pathMatrix() canvas.Matrix {
+	return canvas.Identity.Translate(r.opts.OffsetX, r.ctx.Height()-r.opts.OffsetY).ReflectY().Mul(r.ctx.View())
+}
+
+// addBounds adds the bounds of the painted area of a drawn path, transformed
+// by m and stroked with the given style, to the bounds of the renderer.
+func (r *renderer) addBounds(path *canvas.Path, m canvas.Matrix, style canvas.Style, filled bool, stroked bool) {
+	bounds := paintedBounds(path, m, style, filled, stroked)
+	if r.clip != nil {
+		// Only the part within the bounds of the clip area is painted.
+		clipBounds := r.clip.Bounds()
+		if !bounds.Overlaps(clipBounds) {
+			return
+		}
+		x0, y0 := math.Max(bounds.X, clipBounds.X), math.Max(bounds.Y, clipBounds.Y)
+		x1 := math.Min(bounds.X+bounds.W, clipBounds.X+clipBounds.W)
+		y1 := math.Min(bounds.Y+bounds.H, clipBounds.Y+clipBounds.H)
+		bounds = canvas.Rect{X: x0, Y: y0, W: x1 - x0, H: y1 - y0}
+	}
+	if r.empty {
+		r.bounds = bounds
+		r.empty = false
+	} else {
+		r.bounds = r.bounds.Add(bounds)
+	}
+}
+
+// paintedBounds returns the bounds of the painted area of a path like
+// addBounds.
+func paintedBounds(path *canvas.Path, m canvas.Matrix, style canvas.Style, filled bool, stroked bool) canvas.Rect {
+	if !stroked {
+		return path.Transform(m).Bounds()
+	}
+	bounds := strokeOutline(path, style).Transform(m).Bounds()
+	if filled {
+		bounds = bounds.Add(path.Transform(m).Bounds())
+	}
+	return bounds
+}
+
+// checkBounds reports a path whose painted area is not within the viewport
+// of the vector being drawn as a warning or, with the Strict option, as an
+// error.
+func (r *renderer) checkBounds(pathElem *vectorPath, path *canvas.Path, i int, style canvas.Style, filled bool, stroked bool) error {
+	bounds := paintedBounds(path, r.groups, style, filled, stroked)
+	viewport := canvas.Rect{W: r.vec.ViewportWidth, H: r.vec.ViewportHeight}
+	var problem string
+	if !bounds.Overlaps(viewport) {
+		problem = "lies outside"
+	} else if bounds.X < -canvas.Epsilon || bounds.Y < -canvas.Epsilon ||
+		bounds.X+bounds.W > viewport.W+canvas.Epsilon || bounds.Y+bounds.H > viewport.H+canvas.Epsilon {
+		problem = "extends beyond"
+	} else {
+		return nil
+	}
+
+	label := fmt.Sprintf("path %d", i)
+	if pathElem.Name != "" {
+		label += fmt.Sprintf(" \"%s\"", pathElem.Name)
+	}
+	msg := fmt.Sprintf("%s %s the viewport %gx%g with the bounds %g,%g %gx%g", label, problem,
+		viewport.W, viewport.H, bounds.X, bounds.Y, bounds.W, bounds.H)
+	if r.opts.Strict {
+		return errors.New(msg)
+	}
+	if r.opts.Warn != nil {
+		r.opts.Warn(msg)
+	}
+	return nil
+}
+
+func isTransparent(c color.Color) bool {
+	_, _, _, a := c.RGBA()
+	return a == 0
+}
+
+// fillCanvas fills the whole canvas or the clip area with the paint.
+func (r *renderer) fillCanvas(paint canvas.Paint) {
+	ctx := r.ctx
+	ctx.Push()
+	ctx.ResetView()
+	ctx.SetFill(paint)
+	ctx.SetStrokeColor(canvas.Transparent)
+	ctx.SetFillRule(canvas.NonZero)
+	area := canvas.Rectangle(ctx.Width(), ctx.Height())
+	if r.clip != nil {
+		// The clip area is given in canvas coordinates with the y axis
+		// pointing up.
+		area = r.clip.Transform(canvas.Identity.Translate(0, ctx.Height()).ReflectY())
+	}
+	ctx.DrawPath(0, 0, area)
+	ctx.Pop()
+}
+
+// tintColor applies the tint of the vector or else the tint option, the
+// opacity option and the alpha of the vector to the color c of a path. With
+// a filter, only the alpha of the vectors referencing vec is applied, the
+// filter applies the rest. Colors with channels that had to be clamped are
+// counted.
+func (r *renderer) tintColor(c color.Color) color.Color {
+	tinted, clamped := false, false
+	alpha := r.opts.opacity() * r.alpha
+	if r.filter != nil {
+		alpha = r.alpha
+	} else if r.tint != nil {
+		c, tinted = r.tint.apply(c)
+	} else {
+		c = r.opts.tint(c)
+	}
+	c, clamped = scaleAlpha(c, alpha)
+	if tinted || clamped {
+		r.stats.ColorsClamped++
+	}
+	return c
+}
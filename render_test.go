@@ -0,0 +1,151 @@
+package vectopng
+
+import (
+	"image"
+	"strings"
+	"testing"
+
+	"github.com/tdewolff/canvas"
+)
+
+func TestNormalizePathData(t *testing.T) {
+	tests := []struct {
+		name     string
+		pathData string
+		want     string
+		stripped string
+	}{
+		{"clean", "M10,10h80v80h-80z", "M10,10h80v80h-80z", ""},
+		{"newlines and tabs", "M10,10\n\t\th80\r\n  v80 h-80\nz", "M10,10 h80 v80 h-80 z", ""},
+		{"leading and trailing space", "  M10,10h80v80h-80z \n", "M10,10h80v80h-80z", ""},
+		{"unicode spaces", "M10,10\u00a0h80\u2003v80\u200bh-80\ufeffz", "M10,10 h80 v80 h-80 z", ""},
+		{"unicode minus", "M10,10h80v80h\u221280z", "M10,10h80v80h-80z", ""},
+		{"en dash", "M10,10h80v80h\u201380z", "M10,10h80v80h-80z", ""},
+		{"full-width comma", "M10\uff0c10h80v80h-80z", "M10,10h80v80h-80z", ""},
+		{"stray characters", "M10,10h80;v80h-80z\"", "M10,10h80v80h-80z", ";\""},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			normalized, stripped := normalizePathData(test.pathData)
+			if normalized != test.want {
+				t.Errorf("got %q, want %q", normalized, test.want)
+			}
+			if stripped != test.stripped {
+				t.Errorf("got stripped %q, want %q", stripped, test.stripped)
+			}
+		})
+	}
+}
+
+func TestMessyPathData(t *testing.T) {
+	want := canvas.MustParseSVGPath("M10,10 L90,10 L90,90 L10,90 Z M30,30 L70,30 L70,70 L30,70 Z")
+	tests := []struct {
+		name     string
+		pathData string
+	}{
+		{"missing separators", "M10 10L90 10L90 90L10 90Z M30 30L70 30L70 70L30 70Z"},
+		{"adjacent commands", "M10,10H90V90H10ZM30,30H70V70H30Z"},
+		{"signs as separators", "M10,10h80v80h-80zm20,20h40v40h-40z"},
+		{"pasted lines", "M10,10\n    L90,10\n    L90,90\n    L10,90\n    Z\n    M30,30 L70,30 L70,70 L30,70 Z\n"},
+		{"unicode", "M10,10 L90,10 L90,90 L10,90 Z m20,20 h40 v40 h\u221240 z"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			normalized, _ := normalizePathData(test.pathData)
+			path, err := canvas.ParseSVGPath(normalized)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !path.Equals(want) {
+				t.Errorf("got %v, want %v", path, want)
+			}
+		})
+	}
+}
+
+func TestPathDataErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		pathData string
+		want     string
+	}{
+		{
+			name:     "invalid",
+			pathData: "M10,10L80",
+			want:     `invalid pathData "M10,10L80" of path 0`,
+		},
+		{
+			// Both forms are reported if normalizing changed the path data.
+			name:     "invalid after normalizing",
+			pathData: "M10,10\n  L80",
+			want:     "invalid pathData \"M10,10\n  L80\" (normalized \"M10,10 L80\") of path 0",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := Convert([]byte(testVector(`<path android:fillColor="#000000" android:pathData="`+test.pathData+`"/>`)), Options{})
+			if err == nil || !strings.Contains(err.Error(), test.want) {
+				t.Errorf("got error %v, want %q", err, test.want)
+			}
+		})
+	}
+}
+
+func TestPadding(t *testing.T) {
+	square := `<vector xmlns:android="http://schemas.android.com/apk/res/android"
+    android:width="24dp" android:height="24dp" android:viewportWidth="24" android:viewportHeight="24">
+  <path android:fillColor="#000000" android:pathData="M0,0h24v24h-24z"/>
+</vector>`
+	wide := `<vector xmlns:android="http://schemas.android.com/apk/res/android"
+    android:width="24dp" android:height="12dp" android:viewportWidth="24" android:viewportHeight="12">
+  <path android:fillColor="#000000" android:pathData="M0,0h24v12h-24z"/>
+</vector>`
+	tests := []struct {
+		name   string
+		vector string
+		opts   Options
+		size   image.Point
+	}{
+		{"scale", square, Options{Scale: 1.5, Padding: 3}, image.Point{42, 42}},
+		{"pixel width", square, Options{Scale: 1, PixelWidth: 240, Padding: 8}, image.Point{240, 240}},
+		{"pixel width of a wide canvas", wide, Options{PixelWidth: 240, Padding: 8}, image.Point{240, 128}},
+		{"pixel height", square, Options{PixelHeight: 100, Padding: 8}, image.Point{100, 100}},
+		{"pixel height of a wide canvas", wide, Options{PixelHeight: 100, Padding: 8}, image.Point{184, 100}},
+		{"pixel width and height", square, Options{PixelWidth: 100, PixelHeight: 50, Padding: 8}, image.Point{100, 50}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			img := render(t, test.vector, test.opts)
+			size := img.Bounds().Size()
+			if size != test.size {
+				t.Errorf("got size %v, want %v", size, test.size)
+			}
+			// The padding is transparent and the drawing covers the pixels
+			// right inside it.
+			padding := int(test.opts.Padding)
+			for _, p := range []image.Point{
+				{padding - 1, size.Y / 2}, {size.X - padding, size.Y / 2},
+				{size.X / 2, padding - 1}, {size.X / 2, size.Y - padding},
+			} {
+				if alpha := alphaAt(img, p.X, p.Y); alpha != 0 {
+					t.Errorf("got alpha %d at %v in the padding", alpha, p)
+				}
+			}
+			for _, p := range []image.Point{
+				{padding, size.Y / 2}, {size.X - padding - 1, size.Y / 2},
+				{size.X / 2, padding}, {size.X / 2, size.Y - padding - 1},
+			} {
+				if alpha := alphaAt(img, p.X, p.Y); alpha != 255 {
+					t.Errorf("got alpha %d at %v inside the padding", alpha, p)
+				}
+			}
+		})
+	}
+}
+
+func TestPaddingTooLarge(t *testing.T) {
+	_, err := Convert([]byte(testVector(`<path android:fillColor="#000000" android:pathData="M0,0h100v100h-100z"/>`)), Options{PixelWidth: 16, Padding: 8})
+	if want := "padding 8 must be less than half the pixel width or height of the image"; err == nil || !strings.Contains(err.Error(), want) {
+		t.Errorf("got error %v, want %q", err, want)
+	}
+}
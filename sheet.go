@@ -0,0 +1,86 @@
+package vectopng
+
+import (
+	"image"
+	"image/draw"
+	"math"
+	"sort"
+)
+
+// SpriteSheet packs the images into one sprite sheet and returns it with
+// the rectangle of each image within it. If columns is greater than zero,
+// the images are placed in a grid of that many columns, each in the top
+// left corner of a cell of the size of the largest image. Otherwise they are
+// packed in rows of roughly equal width, the highest images first. spacing
+// is the number of transparent pixels between the images.
+func SpriteSheet(images []image.Image, columns int, spacing int) (*image.RGBA, []image.Rectangle) {
+	sizes := make([]image.Point, len(images))
+	for i, img := range images {
+		sizes[i] = img.Bounds().Size()
+	}
+	var rects []image.Rectangle
+	if columns > 0 {
+		rects = gridLayout(sizes, columns, spacing)
+	} else {
+		rects = rowLayout(sizes, spacing)
+	}
+
+	var bounds image.Rectangle
+	for _, rect := range rects {
+		bounds = bounds.Union(rect)
+	}
+	sheet := image.NewRGBA(image.Rect(0, 0, bounds.Max.X, bounds.Max.Y))
+	for i, img := range images {
+		draw.Draw(sheet, rects[i], img, img.Bounds().Min, draw.Src)
+	}
+	return sheet, rects
+}
+
+// gridLayout places the sizes in cells of the largest size, row by row.
+func gridLayout(sizes []image.Point, columns int, spacing int) []image.Rectangle {
+	var cell image.Point
+	for _, size := range sizes {
+		cell.X = max(cell.X, size.X)
+		cell.Y = max(cell.Y, size.Y)
+	}
+	rects := make([]image.Rectangle, len(sizes))
+	for i, size := range sizes {
+		x := (i % columns) * (cell.X + spacing)
+		y := (i / columns) * (cell.Y + spacing)
+		rects[i] = image.Rect(x, y, x+size.X, y+size.Y)
+	}
+	return rects
+}
+
+// rowLayout places the sizes from the highest to the lowest in rows that are
+// about as wide as the square root of the total area, but at least as wide
+// as the widest size.
+func rowLayout(sizes []image.Point, spacing int) []image.Rectangle {
+	order := make([]int, len(sizes))
+	area := 0
+	width := 0
+	for i, size := range sizes {
+		order[i] = i
+		area += (size.X + spacing) * (size.Y + spacing)
+		width = max(width, size.X)
+	}
+	width = max(width, int(math.Ceil(math.Sqrt(float64(area)))))
+	sort.SliceStable(order, func(a, b int) bool {
+		return sizes[order[a]].Y > sizes[order[b]].Y
+	})
+
+	rects := make([]image.Rectangle, len(sizes))
+	x, y, rowHeight := 0, 0, 0
+	for _, i := range order {
+		size := sizes[i]
+		if x > 0 && x+size.X > width {
+			x = 0
+			y += rowHeight + spacing
+			rowHeight = 0
+		}
+		rects[i] = image.Rect(x, y, x+size.X, y+size.Y)
+		x += size.X + spacing
+		rowHeight = max(rowHeight, size.Y)
+	}
+	return rects
+}
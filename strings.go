@@ -0,0 +1,63 @@
+package vectopng
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrUnresolvedString is returned if a string reference cannot be resolved.
+var ErrUnresolvedString = errors.New("unresolved string reference")
+
+// maxStringDepth limits how many string references are followed, which
+// stops reference cycles.
+const maxStringDepth = 16
+
+type stringDef struct {
+	Name  string `xml:"name,attr"`
+	Value string `xml:",chardata"`
+}
+
+type stringDefsArray struct {
+	Strings []stringDef `xml:"string"`
+}
+
+// StringDefs maps string resource names to their values.
+type StringDefs map[string]string
+
+// ParseStrings parses an Android string resource file and adds its strings
+// to stringDefs. A string of the same name is overridden. Values may
+// reference other strings as @string/name, which are resolved when used.
+func ParseStrings(stringsData []byte, stringDefs StringDefs) error {
+	var stringsArray stringDefsArray
+	if err := xml.Unmarshal(stringsData, &stringsArray); err != nil {
+		return err
+	}
+	for _, stringDef := range stringsArray.Strings {
+		value := strings.TrimSpace(stringDef.Value)
+		if len(value) >= 2 && value[0] == '"' && value[len(value)-1] == '"' {
+			value = value[1 : len(value)-1]
+		}
+		stringDefs[stringDef.Name] = value
+	}
+	return nil
+}
+
+// resolveString returns s or, if it is a @string/name reference, the value
+// of the referenced string. References that cannot be resolved return an
+// error wrapping ErrUnresolvedString.
+func resolveString(s string, stringDefs StringDefs) (string, error) {
+	for depth := 0; depth < maxStringDepth; depth++ {
+		name, ok := strings.CutPrefix(strings.TrimSpace(s), "@string/")
+		if !ok {
+			return s, nil
+		}
+		value, ok := stringDefs[name]
+		if !ok {
+			return "", fmt.Errorf("%w \"@string/%s\"", ErrUnresolvedString, name)
+		}
+		s = value
+	}
+	return "", fmt.Errorf("too many nested string references in \"%s\"", s)
+}
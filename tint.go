@@ -0,0 +1,187 @@
+package vectopng
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+
+	"github.com/tdewolff/canvas"
+)
+
+// vectorTint is the android:tint of the root vector element. Convert
+// composites it with the rendered drawable through a tintFilter, while
+// Bounds and NormalizedPaths, which render nothing, apply it to the color of
+// each path.
+type vectorTint struct {
+	color color.NRGBA
+	mode  string
+}
+
+func parseTint(vec *vector, opts *Options, stats *Stats) (*vectorTint, error) {
+	if vec.Tint == "" {
+		return nil, nil
+	}
+
+	// The tint option replaces the tint color, which therefore does not
+	// have to resolve, as theme attributes such as ?attr/colorControlNormal
+	// never do.
+	c := opts.Tint
+	if c == nil {
+		var err error
+		c, err = opts.color(vec.Tint, stats)
+		if err != nil {
+			return nil, fmt.Errorf("invalid tint: %w", err)
+		}
+	}
+
+	mode := vec.TintMode
+	switch mode {
+	case "":
+		mode = "src_in"
+	case "src_in", "src_over", "src_atop", "multiply", "screen", "add":
+	default:
+		return nil, fmt.Errorf("unsupported tintMode \"%s\"", mode)
+	}
+	return &vectorTint{color.NRGBAModel.Convert(c).(color.NRGBA), mode}, nil
+}
+
+// apply returns the color of a path after tinting it and whether a channel
+// had to be clamped.
+func (t *vectorTint) apply(c color.Color) (color.Color, bool) {
+	n := color.NRGBAModel.Convert(c).(color.NRGBA)
+	ta := float64(t.color.A) / 255
+	tr, tg, tb := float64(t.color.R), float64(t.color.G), float64(t.color.B)
+	r, g, b, a := float64(n.R), float64(n.G), float64(n.B), float64(n.A)
+	switch t.mode {
+	case "src_in":
+		return clampedNRGBA(tr, tg, tb, a*ta)
+	case "src_atop":
+		blend := func(tc, c float64) float64 {
+			return ta*tc + (1-ta)*c
+		}
+		return clampedNRGBA(blend(tr, r), blend(tg, g), blend(tb, b), a)
+	case "multiply":
+		multiply := func(tc, c float64) float64 {
+			return tc * c / 255
+		}
+		return clampedNRGBA(multiply(tr, r), multiply(tg, g), multiply(tb, b), a*ta)
+	case "screen":
+		screen := func(tc, c float64) float64 {
+			tp := ta * tc
+			return tp + c - tp*c/255
+		}
+		return clampedNRGBA(screen(tr, r), screen(tg, g), screen(tb, b), a)
+	case "add":
+		// Adding saturates, which is not counted as clamping.
+		add := func(tc, c float64) float64 {
+			return math.Min(255, ta*tc+c)
+		}
+		return clampedNRGBA(add(tr, r), add(tg, g), add(tb, b), a)
+	}
+	return c, false
+}
+
+// tintFilter marks the paths of a vector with an android:tint on the canvas.
+// Like the color filter of Android, the tint is composited with the whole
+// rendered vector, after which the opacity option and the alpha of the
+// vector are applied. The canvas has no such operation, so two paths that
+// fill the canvas or its clip area with a tintFilter as gradient mark the
+// start and the end of the paths, which pixelRenderer and svgRenderer
+// interpret. Only the modes that paint outside of the paths fill the area at
+// the end with the tint.
+type tintFilter struct {
+	tint  *vectorTint
+	alpha float64
+	end   bool
+}
+
+// SetView returns the filter, which has no position.
+func (f *tintFilter) SetView(view canvas.Matrix) canvas.Gradient {
+	return f
+}
+
+// SetColorSpace returns the filter, which converts the colors itself.
+func (f *tintFilter) SetColorSpace(colorSpace canvas.ColorSpace) canvas.Gradient {
+	return f
+}
+
+// At returns transparent, the marker paths are not painted.
+func (f *tintFilter) At(x, y float64) color.RGBA {
+	return color.RGBA{}
+}
+
+// paintsOutside reports whether the mode paints the tint where nothing was
+// drawn.
+func (f *tintFilter) paintsOutside() bool {
+	mode := f.tint.mode
+	return mode == "src_over" || mode == "screen" || mode == "add"
+}
+
+// draw composites the tint with the drawn layer in sRGB and the result with
+// the alpha of the filter over dst, both in the blending color space. The
+// modes that paint outside of the paths only do so within the coverage of
+// the area.
+func (f *tintFilter) draw(dst *image.RGBA, layer *image.RGBA, area *tile, colorSpace canvas.ColorSpace) {
+	t := f.tint.color
+	bounds := dst.Bounds()
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			sa := float64(t.A) / 255
+			if f.paintsOutside() {
+				sa *= area.coverage(x, y)
+			}
+			d := colorSpace.FromLinear(layer.RGBAAt(x, y))
+			if d.A == 0 && (sa == 0 || !f.paintsOutside()) {
+				continue
+			}
+			// The tint is the source and the layer is the destination of
+			// the Porter-Duff modes, in premultiplied colors.
+			da := float64(d.A) / 255
+			value := func(tc uint8, dc uint8) uint8 {
+				s, d := float64(tc)/255*sa, float64(dc)/255
+				v := 0.0
+				switch f.tint.mode {
+				case "src_in":
+					v = s * da
+				case "src_over":
+					v = s + d*(1-sa)
+				case "src_atop":
+					v = s*da + d*(1-sa)
+				case "multiply":
+					v = s * d
+				case "screen":
+					v = s + d - s*d
+				case "add":
+					v = s + d
+				}
+				return uint8(math.Round(255 * math.Max(0, math.Min(1, v)*f.alpha)))
+			}
+			c := colorSpace.ToLinear(color.RGBA{value(t.R, d.R), value(t.G, d.G), value(t.B, d.B), value(255, d.A)})
+			b := dst.RGBAAt(x, y)
+			a := 255 - uint32(c.A)
+			dst.SetRGBA(x, y, color.RGBA{
+				uint8(uint32(c.R) + (uint32(b.R)*a+127)/255),
+				uint8(uint32(c.G) + (uint32(b.G)*a+127)/255),
+				uint8(uint32(c.B) + (uint32(b.B)*a+127)/255),
+				uint8(uint32(c.A) + (uint32(b.A)*a+127)/255),
+			})
+		}
+	}
+}
+
+// svg returns the SVG filter with the id that composites the tint with the
+// filtered element.
+func (f *tintFilter) svg(id string, width, height float64) string {
+	tint, alpha := hexColor(f.tint.color)
+	primitive := map[string]string{
+		"src_in":   `<feComposite in="tint" in2="SourceGraphic" operator="in"/>`,
+		"src_over": `<feComposite in="tint" in2="SourceGraphic" operator="over"/>`,
+		"src_atop": `<feComposite in="tint" in2="SourceGraphic" operator="atop"/>`,
+		"multiply": `<feComposite in="tint" in2="SourceGraphic" operator="arithmetic" k1="1" k2="0" k3="0" k4="0"/>`,
+		"screen":   `<feBlend in="tint" in2="SourceGraphic" mode="screen"/>`,
+		"add":      `<feComposite in="tint" in2="SourceGraphic" operator="arithmetic" k1="0" k2="1" k3="1" k4="0"/>`,
+	}[f.tint.mode]
+	return fmt.Sprintf(`<filter id="%s" filterUnits="userSpaceOnUse" x="0" y="0" width="%g" height="%g" color-interpolation-filters="sRGB"><feFlood flood-color="%s" flood-opacity="%g" result="tint"/>%s</filter>`,
+		id, width, height, tint, alpha, primitive)
+}
@@ -0,0 +1,106 @@
+package vectopng
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"strings"
+	"testing"
+)
+
+// testTintVector returns a vector whose left half is red, with the tint
+// attributes given.
+func testTintVector(attrs string) string {
+	return `<vector xmlns:android="http://schemas.android.com/apk/res/android"
+    android:width="100dp" android:height="100dp"
+    android:viewportWidth="100" android:viewportHeight="100" ` + attrs + `>
+  <path android:pathData="M0,0h50v100h-50z" android:fillColor="#ff0000"/>
+</vector>`
+}
+
+func TestTintFilter(t *testing.T) {
+	tests := []struct {
+		name    string
+		attrs   string
+		inside  color.RGBA
+		outside color.RGBA
+	}{
+		{
+			// The tint replaces the color where the drawable is painted.
+			name:    "src_in",
+			attrs:   `android:tint="#8000ff00" android:tintMode="src_in"`,
+			inside:  color.RGBA{0, 128, 0, 128},
+			outside: color.RGBA{},
+		},
+		{
+			// The tint is drawn over the whole drawable.
+			name:    "src_over",
+			attrs:   `android:tint="#8000ff00" android:tintMode="src_over"`,
+			inside:  color.RGBA{127, 128, 0, 255},
+			outside: color.RGBA{0, 128, 0, 128},
+		},
+		{
+			// The alpha of the vector applies after the tint.
+			name:    "src_over with alpha",
+			attrs:   `android:tint="#8000ff00" android:tintMode="src_over" android:alpha="0.5"`,
+			inside:  color.RGBA{64, 64, 0, 128},
+			outside: color.RGBA{0, 64, 0, 64},
+		},
+		{
+			name:    "multiply",
+			attrs:   `android:tint="#ff808080" android:tintMode="multiply"`,
+			inside:  color.RGBA{128, 0, 0, 255},
+			outside: color.RGBA{},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			img := render(t, testTintVector(test.attrs), Options{}).(*image.RGBA)
+			if got := img.RGBAAt(25, 50); got != test.inside {
+				t.Errorf("got %v inside, want %v", got, test.inside)
+			}
+			if got := img.RGBAAt(75, 50); got != test.outside {
+				t.Errorf("got %v outside, want %v", got, test.outside)
+			}
+		})
+	}
+}
+
+func TestTintFilterOverlap(t *testing.T) {
+	// A tinted drawable is faded as a whole like on Android, so the overlap
+	// of its paths is not more opaque than the rest of them.
+	xmlData := `<vector xmlns:android="http://schemas.android.com/apk/res/android"
+    android:width="100dp" android:height="100dp"
+    android:viewportWidth="100" android:viewportHeight="100"
+    android:tint="#0000ff" android:tintMode="src_in" android:alpha="0.5">
+  <path android:pathData="M0,0h60v100h-60z" android:fillColor="#ff0000"/>
+  <path android:pathData="M40,0h60v100h-60z" android:fillColor="#00ff00"/>
+</vector>`
+	img := render(t, xmlData, Options{}).(*image.RGBA)
+	for _, x := range []int{20, 50, 80} {
+		if got, want := img.RGBAAt(x, 50), (color.RGBA{0, 0, 128, 128}); got != want {
+			t.Errorf("got %v at %d, want %v", got, x, want)
+		}
+	}
+}
+
+func TestSVGTintFilter(t *testing.T) {
+	c, err := Convert([]byte(testTintVector(`android:tint="#8000ff00" android:tintMode="src_over" android:alpha="0.5"`)), Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if _, err := Write(&buf, c, FormatSVG, Options{}); err != nil {
+		t.Fatal(err)
+	}
+	svg := buf.String()
+	for _, s := range []string{
+		`<feFlood flood-color="#00ff00" flood-opacity="0.50`,
+		`<feComposite in="tint" in2="SourceGraphic" operator="over"/>`,
+		`<g filter="url(#tint1)" clip-path="url(#tintclip1)" opacity="0.5"><path d="M0 0H50V100H0z" fill="#f00"/></g>`,
+	} {
+		if !strings.Contains(svg, s) {
+			t.Errorf("SVG lacks %s:\n%s", s, svg)
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package vectopng
+
+import (
+	"math"
+
+	"github.com/tdewolff/canvas"
+)
+
+// trimPath returns the part of the path from start to end, shifted by
+// offset, as fractions of its length. Like on Android, only the first
+// subpath is measured and trimmed while the others are left out, and a part
+// wrapping around the end continues at the start as a separate subpath.
+// The path is returned as is if it is not trimmed, even with an offset.
+func trimPath(path *canvas.Path, start float64, end float64, offset float64) *canvas.Path {
+	if start == 0 && end == 1 {
+		return path
+	}
+	subpaths := path.Split()
+	if len(subpaths) == 0 {
+		return path
+	}
+	first := subpaths[0]
+	length := first.Length()
+	start = math.Mod(start+offset, 1) * length
+	end = math.Mod(end+offset, 1) * length
+	if start > end {
+		return pathSegment(first, length, start, length).Append(pathSegment(first, length, 0, end))
+	}
+	return pathSegment(first, length, start, end)
+}
+
+// pathSegment returns the part of the subpath of the given length between
+// the distances from and to along it, which is empty if from is not before
+// to.
+func pathSegment(subpath *canvas.Path, length float64, from float64, to float64) *canvas.Path {
+	from, to = math.Max(from, 0), math.Min(to, length)
+	if !(from < to) {
+		return &canvas.Path{}
+	}
+	// Path.SplitAt drops a split at zero and only splits at the length if
+	// it matches its own measurement.
+	var parts []*canvas.Path
+	if to < length {
+		parts = subpath.SplitAt(from, to)
+	} else {
+		parts = subpath.SplitAt(from)
+	}
+	if from > 0 {
+		parts = parts[1:]
+	}
+	if len(parts) == 0 {
+		return &canvas.Path{}
+	}
+	return parts[0]
+}
@@ -0,0 +1,190 @@
+package vectopng
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"math"
+)
+
+const (
+	androidNamespace = "http://schemas.android.com/apk/res/android"
+	aaptNamespace    = "http://schemas.android.com/aapt"
+	toolsNamespace   = "http://schemas.android.com/tools"
+)
+
+// supportedAttrs lists the attributes each supported element may have.
+// Attributes without effect on the rendering such as android:name are
+// included so that they do not cause warnings.
+var supportedAttrs = map[string]map[string]bool{
+	"vector": {
+		"name":           true,
+		"width":          true,
+		"height":         true,
+		"viewportWidth":  true,
+		"viewportHeight": true,
+		"tint":           true,
+		"tintMode":       true,
+		"autoMirrored":   true,
+		"alpha":          true,
+	},
+	"group": {
+		"name":       true,
+		"rotation":   true,
+		"pivotX":     true,
+		"pivotY":     true,
+		"scaleX":     true,
+		"scaleY":     true,
+		"translateX": true,
+		"translateY": true,
+	},
+	"clip-path": {
+		"name":     true,
+		"pathData": true,
+		"fillType": true,
+	},
+	"path": {
+		"name":             true,
+		"fillColor":        true,
+		"strokeColor":      true,
+		"strokeWidth":      true,
+		"fillAlpha":        true,
+		"strokeAlpha":      true,
+		"strokeLineCap":    true,
+		"strokeLineJoin":   true,
+		"strokeMiterLimit": true,
+		"fillType":         true,
+		"trimPathStart":    true,
+		"trimPathEnd":      true,
+		"trimPathOffset":   true,
+		"strokeDashArray":  true,
+		"strokeDashOffset": true,
+		"pathData":         true,
+		"drawable":         true,
+	},
+	"aapt:attr": {
+		"name": true,
+	},
+	"gradient": {
+		"type":           true,
+		"tileMode":       true,
+		"startX":         true,
+		"startY":         true,
+		"endX":           true,
+		"endY":           true,
+		"centerX":        true,
+		"centerY":        true,
+		"gradientRadius": true,
+		"startColor":     true,
+		"centerColor":    true,
+		"endColor":       true,
+	},
+	"item": {
+		"offset": true,
+		"color":  true,
+	},
+}
+
+// supportedAaptAttrs lists the attributes that can be given as <aapt:attr>.
+var supportedAaptAttrs = map[string]bool{
+	"android:fillColor":   true,
+	"android:strokeColor": true,
+}
+
+func (vec *vector) validate() error {
+	if vec.ViewportWidth <= 0 {
+		return errors.New("viewportWidth must be greater than zero")
+	}
+	if vec.ViewportHeight <= 0 {
+		return errors.New("viewportHeight must be greater than zero")
+	}
+	if math.IsNaN(vec.Alpha) || vec.Alpha < 0 || vec.Alpha > 1 {
+		return fmt.Errorf("alpha %g is outside of 0 to 1", vec.Alpha)
+	}
+	return nil
+}
+
+// checkSupported walks the XML tokens of a vector drawable and returns a
+// warning for each distinct element or attribute that is not supported and
+// therefore ignored. The contents of unsupported elements are not checked.
+func checkSupported(xmlData []byte) ([]string, error) {
+	var warnings []string
+	seen := make(map[string]bool)
+	warn := func(format string, a ...any) {
+		msg := fmt.Sprintf(format, a...)
+		if !seen[msg] {
+			seen[msg] = true
+			warnings = append(warnings, msg)
+		}
+	}
+
+	// Only the elements of the vector are checked, not those around it in an
+	// animated vector.
+	decoder := newDecoder(xmlData)
+	start, err := findVector(decoder)
+	if err != nil {
+		return nil, err
+	}
+	var token xml.Token = start
+	skipDepth := 0
+	for depth := 0; ; token, err = decoder.Token() {
+		if err != nil {
+			return nil, err
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			depth++
+			if skipDepth > 0 {
+				skipDepth++
+				continue
+			}
+			name := t.Name.Local
+			if t.Name.Space == aaptNamespace {
+				name = qualifiedName(t.Name)
+			} else if !isAndroidSpace(t.Name.Space) {
+				name = ""
+			}
+			attrs, ok := supportedAttrs[name]
+			if !ok {
+				warn("unsupported element <%s>", qualifiedName(t.Name))
+				skipDepth = 1
+				continue
+			}
+			if name == "aapt:attr" && !supportedAaptAttrs[attrValue(t, "name")] {
+				warn("unsupported <aapt:attr name=\"%s\">", attrValue(t, "name"))
+				skipDepth = 1
+				continue
+			}
+			for _, attr := range t.Attr {
+				// Tools attributes are only used by the layout editor.
+				if attr.Name.Space == "xmlns" || attr.Name.Local == "xmlns" || attr.Name.Space == toolsNamespace {
+					continue
+				}
+				if !attrs[attr.Name.Local] || !isAndroidSpace(attr.Name.Space) {
+					warn("unsupported attribute %s of <%s>", qualifiedName(attr.Name), t.Name.Local)
+				}
+			}
+		case xml.EndElement:
+			depth--
+			if skipDepth > 0 {
+				skipDepth--
+			}
+		}
+		if depth == 0 {
+			return warnings, nil
+		}
+	}
+}
+
+// qualifiedName returns the name with the usual prefix of its namespace.
+func qualifiedName(name xml.Name) string {
+	switch name.Space {
+	case "":
+		return name.Local
+	case androidNamespace:
+		return "android:" + name.Local
+	case aaptNamespace:
+		return "aapt:" + name.Local
+	}
+	return name.Space + ":" + name.Local
+}
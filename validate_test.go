@@ -0,0 +1,52 @@
+package vectopng
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidateViewport(t *testing.T) {
+	tests := []struct {
+		name  string
+		attrs string
+		want  string
+	}{
+		{
+			name:  "zero width",
+			attrs: `android:width="24dp" android:height="24dp" android:viewportWidth="0" android:viewportHeight="24"`,
+			want:  "viewportWidth must be greater than zero",
+		},
+		{
+			name:  "zero height",
+			attrs: `android:width="24dp" android:height="24dp" android:viewportWidth="24" android:viewportHeight="0"`,
+			want:  "viewportHeight must be greater than zero",
+		},
+		{
+			name:  "negative width",
+			attrs: `android:width="24dp" android:height="24dp" android:viewportWidth="-24" android:viewportHeight="24"`,
+			want:  "viewportWidth must be greater than zero",
+		},
+		{
+			// A missing viewport dimension follows the size, which is zero.
+			name:  "missing width",
+			attrs: `android:width="0dp" android:height="24dp" android:viewportHeight="24"`,
+			want:  "viewportWidth must be greater than zero",
+		},
+		{
+			name:  "missing height",
+			attrs: `android:width="24dp" android:viewportWidth="24"`,
+			want:  "viewportHeight must be greater than zero",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			xmlData := `<vector xmlns:android="http://schemas.android.com/apk/res/android" ` + test.attrs + `>
+  <path android:fillColor="#000000" android:pathData="M0,0h1v1h-1z"/>
+</vector>`
+			_, err := Convert([]byte(xmlData), Options{})
+			if err == nil || !strings.Contains(err.Error(), test.want) {
+				t.Errorf("got error %v, want %s", err, test.want)
+			}
+		})
+	}
+}
@@ -1,24 +1,235 @@
-package main
+// Package vectopng converts Android vector drawables to raster images.
+package vectopng
 
 import (
+	"bytes"
 	"encoding/xml"
-	"flag"
+	"errors"
 	"fmt"
 	"image/color"
-	"os"
+	"io"
+	"math"
 	"path/filepath"
 	"regexp"
+	"slices"
 	"strconv"
 	"strings"
 
 	"github.com/tdewolff/canvas"
-	"github.com/tdewolff/canvas/renderers"
 )
 
-const version = "1.0"
+// ErrNotVector is returned by Convert if the XML data is not an Android
+// vector drawable.
+var ErrNotVector = errors.New("not a valid Android vector drawable")
+
+// androidDensities maps the Android densities to their scale factors.
+var androidDensities = []struct {
+	Name  string
+	Scale float64
+}{
+	{"mdpi", 1},
+	{"hdpi", 1.5},
+	{"xhdpi", 2},
+	{"xxhdpi", 3},
+	{"xxxhdpi", 4},
+}
 
-var dpNumPattern = regexp.MustCompile(`(\d+)dp`)
-var colorPattern = regexp.MustCompile(`^#([0-9a-fA-F]{3,8})$`)
+// The default output templates of the image at the requested scale, the
+// versions at other scales such as those for iOS and the Android densities.
+const (
+	defaultTemplate = "{name}{ext}"
+	scaleTemplate   = "{name}@{scale}x{ext}"
+	androidTemplate = "drawable-{density}/{name}{ext}"
+)
+
+var dimensionPattern = regexp.MustCompile(`^((?:\d+(?:\.\d*)?|\.\d+)(?:[eE][-+]?\d+)?)([a-zA-Z%]*)$`)
+
+// DefaultMaxPixels is the largest number of pixels of a raster image that
+// Save writes by default.
+const DefaultMaxPixels = 64 * 1000 * 1000
+
+// mdpi is the screen density in dpi at which one px is one dp.
+const mdpi = 160.0
+
+// Options controls how a vector drawable is converted and saved.
+type Options struct {
+	// Scale scales the saved image by the given factor. Values <= 0 are
+	// treated as 1.
+	Scale float64
+	// ScaleY scales the image vertically instead of Scale if greater than
+	// zero, so that Scale only applies horizontally.
+	ScaleY float64
+	// PixelWidth and PixelHeight define the exact pixel size of the saved
+	// image if greater than zero and override Scale. If only one of them is
+	// given, the other follows from the aspect ratio of the canvas. If both
+	// are given, the drawing is stretched to fill the requested size.
+	PixelWidth  int
+	PixelHeight int
+	// MaxPixels limits the number of pixels of the raster images written
+	// by Save, which fails before allocating a larger image. If zero,
+	// DefaultMaxPixels applies, negative values disable the limit.
+	MaxPixels int
+	// Colors defines the colors that can be referenced by name. Colors is
+	// only read, so the same definitions can be shared by concurrent
+	// conversions once they are complete.
+	Colors ColorDefs
+	// Strings defines the string resources that pathData and color
+	// attributes can reference as @string/name. Like Colors, it is only
+	// read.
+	Strings StringDefs
+	// DrawableDir is the directory that the android:drawable references
+	// of paths such as @drawable/ic_badge are read from, as ic_badge.xml.
+	// The referenced vector drawable is drawn in place of the path.
+	DrawableDir string
+	// DefaultColor is used for color references that cannot be resolved.
+	// If nil, unresolved references are an error.
+	DefaultColor color.Color
+	// OutputTemplate defines the names of the saved images relative to the
+	// directory of the file given to Save. The placeholders {name} and {ext}
+	// stand for the file name without extension and the extension including
+	// the dot, {scale} for the factor of a version (1 for the image itself)
+	// and {density} for the Android density such as xhdpi (empty for other
+	// images). If empty, the images are named like the file, with @{scale}x
+	// suffixes for other scales and in drawable-{density} folders for
+	// Android.
+	OutputTemplate string
+	// Scales saves a version of the image for each factor, named with an
+	// @<factor>x suffix such as @1.5x, instead of the image itself and the
+	// iOS versions. The factors multiply Scale.
+	Scales []float64
+	// IOS additionally saves @2x and @3x versions of the image.
+	IOS bool
+	// IOSScales replaces the 1x, 2x and 3x factors of the iOS versions. The
+	// 1x version is the image itself, without suffix.
+	IOSScales []float64
+	// IOSBase is the iOS factor of the image at Scale, 1 if not greater
+	// than zero, so that 3 takes the drawable as @3x and scales down the rest.
+	IOSBase float64
+	// Format defines the image format. If empty, it follows from the file
+	// extension.
+	Format Format
+	// ICOSizes defines the sizes of the images in ICO files. If empty,
+	// DefaultICOSizes are used.
+	ICOSizes []int
+	// DPI defines the density stored in PNG images if greater than zero. It
+	// is metadata only, the pixel size follows from Scale.
+	DPI float64
+	// ColorProfile is an ICC profile embedded in PNG images. If nil, PNG
+	// images are tagged as sRGB, the color space the colors of vector
+	// drawables are given in, unless NoColorProfile is set.
+	ColorProfile   []byte
+	NoColorProfile bool
+	// Quality defines the quality (1-100) of JPEG images. For the lossless
+	// WebP images, it trades a longer encoding time for a smaller file
+	// instead. Values <= 0 select the default quality.
+	Quality int
+	// Tint replaces the color of all paths while keeping their alpha. If
+	// the vector has an android:tint, it replaces the color of that tint
+	// instead, which is then applied with the android:tintMode. If nil, the
+	// paths keep their colors.
+	Tint color.Color
+	// Opacity multiplies the alpha of all paths. Values <= 0 are treated as
+	// 1, values > 1 are an error.
+	Opacity float64
+	// Background fills the canvas before the paths are drawn. If nil, the
+	// background is transparent, except for JPEG images where it is white.
+	Background color.Color
+	// Flatten composites PNG, JPEG and WebP images over this matte color
+	// so that they have no alpha channel at all, which some print workflows
+	// require. Unlike Background, it applies to the rasterized image and
+	// the alpha of the color is ignored. If nil, nothing is flattened.
+	Flatten color.Color
+	// RTL mirrors auto-mirrored vector drawables horizontally for
+	// right-to-left layouts. Other drawables are not changed.
+	RTL bool
+	// Android saves the image in the Android density folders drawable-mdpi
+	// through drawable-xxxhdpi next to it instead of the image itself. With
+	// IOS or Scales, the densities are saved in addition to their versions.
+	Android bool
+	// Width and Height override the canvas size of the vector drawable if
+	// greater than zero.
+	Width  float64
+	Height float64
+	// Viewport maps the given region of the viewport to the canvas instead
+	// of the whole viewport if its size is not zero, which crops or zooms
+	// into the drawing. The canvas size does not change, so a region with
+	// another aspect ratio is stretched unless PreserveAspect is set. With
+	// layers, the region refers to the viewport of the first vector.
+	Viewport canvas.Rect
+	// PreserveAspect scales the drawing uniformly to fit the canvas and
+	// centers it instead of stretching the viewport to the canvas size.
+	PreserveAspect bool
+	// UniformStroke draws strokes with the same width in both directions if
+	// the viewport is stretched by different factors horizontally and
+	// vertically. The width is scaled by the smaller factor, like Android
+	// does. By default, strokes are stretched with the drawing.
+	UniformStroke bool
+	// AdaptiveMask crops the canvas to the visible 72 of 108 dp of an
+	// adaptive launcher icon and clips it to the mask shape if not empty.
+	// Trim has no effect then.
+	AdaptiveMask Mask
+	// Trim crops the canvas to the bounds of the drawn paths.
+	Trim bool
+	// Padding adds a margin around the canvas after it has been cropped
+	// by AdaptiveMask or Trim. It is given in pixels of the saved image or,
+	// if PaddingPercent is set, in percent of the larger side of the
+	// canvas. An image of a given PixelWidth or PixelHeight keeps its size
+	// and the drawing is scaled to the pixels inside the padding. The margin
+	// is transparent or filled with Background and is adjusted so that the
+	// image has a whole number of pixels.
+	Padding        float64
+	PaddingPercent bool
+	// NoAntialias draws the paths without anti-aliasing, so that every
+	// pixel is either fully covered or not at all. Edges become jagged, but
+	// stay crisp in small images such as pixel art.
+	NoAntialias bool
+	// PixelSnap rounds the path coordinates to the pixel grid of each saved
+	// image, which makes axis-aligned edges sharp. Shapes may shift or change
+	// size by up to half a pixel.
+	PixelSnap bool
+	// Density is the screen density in dpi that px dimensions refer to. It
+	// defaults to 160 (mdpi), where one px is one dp. The physical units mm,
+	// in and pt do not depend on it.
+	Density float64
+	// Only draws just the paths with the given android:name attributes if
+	// not empty. Exclude leaves out the paths with the given names. Names
+	// that no path has are reported as warnings.
+	Only    []string
+	Exclude []string
+	// SnapScale stretches the drawing of raster images slightly so that it
+	// fills the image exactly. Otherwise the last row or column of pixels is
+	// only partly covered where the canvas size times the scale is not a
+	// whole number of pixels, which shows as a seam at the edge of full
+	// bleed backgrounds.
+	SnapScale bool
+	// LinearBlend blends semi-transparent colors in linear RGB instead of
+	// directly in sRGB, which is physically correct and lightens overlaps of
+	// dark colors. By default the colors are blended in sRGB like by most
+	// browsers and SVG renderers.
+	LinearBlend bool
+	// GradientInterpolation is the color space in which the colors between
+	// the stops of gradients are computed. If empty, the sRGB values are
+	// mixed like on Android.
+	GradientInterpolation Interpolation
+	// Strict turns warnings about unsupported elements and attributes into
+	// errors.
+	Strict bool
+	// CheckBounds warns about paths whose painted area lies wholly or
+	// partly outside the viewport, or fails with Strict.
+	CheckBounds bool
+	// PathCache reuses the paths parsed and rasterized for earlier
+	// conversions that share it if not nil. The rasterized paths are not
+	// reused with NoAntialias.
+	PathCache *PathCache
+	// Warn is called with warnings such as unsupported elements and
+	// attributes, which are ignored. If nil, warnings are discarded.
+	Warn func(warning string)
+	// Stats receives the statistics of the rendering if not nil.
+	Stats *Stats
+	// OffsetX and OffsetY translate the image.
+	OffsetX float64
+	OffsetY float64
+}
 
 type vector struct {
 	XMLName        xml.Name
@@ -26,292 +237,646 @@ type vector struct {
 	Height         string       `xml:"height,attr"`
 	ViewportWidth  float64      `xml:"viewportWidth,attr"`
 	ViewportHeight float64      `xml:"viewportHeight,attr"`
-	Paths          []vectorPath `xml:"path"`
+	Tint           string       `xml:"tint,attr"`
+	TintMode       string       `xml:"tintMode,attr"`
+	AutoMirrored   bool         `xml:"autoMirrored,attr"`
+	Alpha          float64      `xml:"alpha,attr"`
+	Children       []vectorNode `xml:",any"`
 }
 
-type vectorPath struct {
-	FillColor   string  `xml:"fillColor,attr"`
-	StrokeColor string  `xml:"strokeColor,attr"`
-	StrokeWidth float64 `xml:"strokeWidth,attr"`
-	PathData    string  `xml:"pathData,attr"`
+// vectorNode is a child element of a vector or group. The children are kept
+// in document order, which is the drawing order. Only one of the fields is
+// set, unsupported elements leave all nil.
+type vectorNode struct {
+	Path     *vectorPath
+	Group    *vectorGroup
+	ClipPath *vectorClipPath
 }
 
-type transform struct {
-	Width   float64
-	Height  float64
-	OffsetX float64
-	OffsetY float64
+func (v *vector) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	type plainVector vector
+	start.Attr = androidAttrs(start.Attr)
+	v.Alpha = 1
+	if err := d.DecodeElement((*plainVector)(v), &start); err != nil {
+		return err
+	}
+
+	// A missing viewport dimension maps one viewport unit to one dp at the
+	// default density.
+	hasAttr := func(name string) bool {
+		for _, attr := range start.Attr {
+			if attr.Name.Local == name {
+				return true
+			}
+		}
+		return false
+	}
+	if !hasAttr("viewportWidth") {
+		if width, err := parseDimension(v.Width, "width", 0); err == nil {
+			v.ViewportWidth = width
+		}
+	}
+	if !hasAttr("viewportHeight") {
+		if height, err := parseDimension(v.Height, "height", 0); err == nil {
+			v.ViewportHeight = height
+		}
+	}
+	return nil
 }
 
-type colorDef struct {
-	Name  string `xml:"name,attr"`
-	Color string `xml:",chardata"`
+func (n *vectorNode) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	start.Attr = androidAttrs(start.Attr)
+	switch start.Name.Local {
+	case "path":
+		n.Path = &vectorPath{FillAlpha: 1, StrokeAlpha: 1, StrokeMiterLimit: 4, TrimPathEnd: 1}
+		return d.DecodeElement(n.Path, &start)
+	case "group":
+		n.Group = &vectorGroup{ScaleX: 1, ScaleY: 1}
+		return d.DecodeElement(n.Group, &start)
+	case "clip-path":
+		n.ClipPath = &vectorClipPath{}
+		return d.DecodeElement(n.ClipPath, &start)
+	}
+	return d.Skip()
 }
 
-type colorDefsArray struct {
-	Colors []colorDef `xml:"color"`
+// androidAttrs returns the attributes in the android namespace, whatever
+// prefix it is declared with, and the attributes without a namespace. If an
+// attribute is given both ways, the one in the android namespace is used.
+// Attributes in other namespaces such as tools:fillColor are dropped, while
+// the struct tags alone would match them by their local name.
+func androidAttrs(attrs []xml.Attr) []xml.Attr {
+	var result []xml.Attr
+	indexes := make(map[string]int)
+	for _, attr := range attrs {
+		if !isAndroidSpace(attr.Name.Space) {
+			continue
+		}
+		if i, ok := indexes[attr.Name.Local]; !ok {
+			indexes[attr.Name.Local] = len(result)
+			result = append(result, attr)
+		} else if attr.Name.Space != "" {
+			result[i] = attr
+		}
+	}
+	return result
 }
 
-type colorDefs map[string]color.Color
+// isAndroidSpace reports whether the namespace of an element or attribute
+// is the android namespace or none. An undeclared android prefix is accepted
+// as well.
+func isAndroidSpace(space string) bool {
+	return space == "" || space == androidNamespace || space == "android"
+}
 
-func (cd *colorDefs) String() string {
-	return ""
+// vectorGroup transforms its children by scaling and rotating them around
+// the pivot point and then translating them, in this order like on Android.
+// newDecoder returns a decoder of the vector drawable in xmlData that
+// rewrites the name of each <aapt:attr> to the android prefix if its prefix
+// is bound to the android namespace, such as a:fillColor with
+// xmlns:a="http://schemas.android.com/apk/res/android". The decoder
+// resolves the namespaces of element and attribute names itself, but not
+// those in attribute values.
+func newDecoder(xmlData []byte) *xml.Decoder {
+	return xml.NewTokenDecoder(&aaptNameReader{d: xml.NewDecoder(bytes.NewReader(xmlData))})
 }
 
-func (cd *colorDefs) Set(value string) error {
-	parts := strings.SplitN(value, "=", 2)
-	if len(parts) != 2 {
-		return fmt.Errorf("invalid color definition \"%s\"", value)
-	}
-	color, err := parseColor(strings.TrimSpace(parts[1]), nil)
+// aaptNameReader reads the raw tokens of a decoder and keeps track of the
+// namespace prefixes declared by the open elements.
+type aaptNameReader struct {
+	d      *xml.Decoder
+	scopes []map[string]string
+}
+
+func (r *aaptNameReader) Token() (xml.Token, error) {
+	token, err := r.d.RawToken()
 	if err != nil {
-		return fmt.Errorf("invalid color definition \"%s\"", value)
+		return nil, err
+	}
+	token = xml.CopyToken(token)
+	switch t := token.(type) {
+	case xml.StartElement:
+		scope := make(map[string]string)
+		for _, attr := range t.Attr {
+			if attr.Name.Space == "xmlns" {
+				scope[attr.Name.Local] = attr.Value
+			}
+		}
+		r.scopes = append(r.scopes, scope)
+		if space := r.namespace(t.Name.Space); t.Name.Local == "attr" && (space == aaptNamespace || space == "aapt") {
+			for i, attr := range t.Attr {
+				prefix, local, ok := strings.Cut(attr.Value, ":")
+				if attr.Name.Space == "" && attr.Name.Local == "name" && ok && r.namespace(prefix) == androidNamespace {
+					t.Attr[i].Value = "android:" + local
+				}
+			}
+		}
+	case xml.EndElement:
+		if len(r.scopes) > 0 {
+			r.scopes = r.scopes[:len(r.scopes)-1]
+		}
+	}
+	return token, nil
+}
+
+// namespace returns the namespace the prefix is bound to by the innermost
+// open element declaring it, or the prefix itself if it is not declared.
+func (r *aaptNameReader) namespace(prefix string) string {
+	for i := len(r.scopes) - 1; i >= 0; i-- {
+		if space, ok := r.scopes[i][prefix]; ok {
+			return space
+		}
 	}
+	return prefix
+}
+
+type vectorGroup struct {
+	Name       string       `xml:"name,attr" json:"name,omitempty"`
+	Rotation   float64      `xml:"rotation,attr" json:"rotation,omitempty"`
+	PivotX     float64      `xml:"pivotX,attr" json:"pivotX,omitempty"`
+	PivotY     float64      `xml:"pivotY,attr" json:"pivotY,omitempty"`
+	ScaleX     float64      `xml:"scaleX,attr" json:"scaleX"`
+	ScaleY     float64      `xml:"scaleY,attr" json:"scaleY"`
+	TranslateX float64      `xml:"translateX,attr" json:"translateX,omitempty"`
+	TranslateY float64      `xml:"translateY,attr" json:"translateY,omitempty"`
+	Children   []vectorNode `xml:",any" json:"children"`
+}
+
+// matrix returns the transformation of the group.
+func (g *vectorGroup) matrix() canvas.Matrix {
+	return canvas.Identity.
+		Translate(g.TranslateX+g.PivotX, g.TranslateY+g.PivotY).
+		Rotate(g.Rotation).
+		Scale(g.ScaleX, g.ScaleY).
+		Translate(-g.PivotX, -g.PivotY)
+}
+
+// vectorClipPath clips the following nodes of its group, including nested
+// groups, to the area of its path.
+type vectorClipPath struct {
+	Name     string `xml:"name,attr" json:"name,omitempty"`
+	PathData string `xml:"pathData,attr" json:"pathData"`
+	// FillType is nonZero, the default, or evenOdd like for paths.
+	FillType string `xml:"fillType,attr" json:"fillType,omitempty"`
+}
 
-	name := strings.TrimSpace(parts[0])
-	(*cd)[name] = color
+type vectorPath struct {
+	Name        string  `xml:"name,attr" json:"name,omitempty"`
+	FillColor   string  `xml:"fillColor,attr" json:"fillColor,omitempty"`
+	StrokeColor string  `xml:"strokeColor,attr" json:"strokeColor,omitempty"`
+	StrokeWidth float64 `xml:"strokeWidth,attr" json:"strokeWidth,omitempty"`
+	// FillAlpha and StrokeAlpha are multiplied into the alpha of the fill
+	// and the stroke, they default to 1.
+	FillAlpha   float64 `xml:"fillAlpha,attr" json:"fillAlpha"`
+	StrokeAlpha float64 `xml:"strokeAlpha,attr" json:"strokeAlpha"`
+	// StrokeLineCap is butt, the default, round or square, StrokeLineJoin
+	// is miter, the default, round or bevel. StrokeMiterLimit is the
+	// longest miter join relative to the stroke width, it defaults to 4.
+	StrokeLineCap    string  `xml:"strokeLineCap,attr" json:"strokeLineCap,omitempty"`
+	StrokeLineJoin   string  `xml:"strokeLineJoin,attr" json:"strokeLineJoin,omitempty"`
+	StrokeMiterLimit float64 `xml:"strokeMiterLimit,attr" json:"strokeMiterLimit"`
+	// FillType is nonZero, the default, or evenOdd.
+	FillType string `xml:"fillType,attr" json:"fillType,omitempty"`
+	// TrimPathStart and TrimPathEnd are the fractions of the length of the
+	// path that are drawn, shifted by TrimPathOffset. TrimPathEnd defaults
+	// to 1.
+	TrimPathStart  float64 `xml:"trimPathStart,attr" json:"trimPathStart,omitempty"`
+	TrimPathEnd    float64 `xml:"trimPathEnd,attr" json:"trimPathEnd"`
+	TrimPathOffset float64 `xml:"trimPathOffset,attr" json:"trimPathOffset,omitempty"`
+	// StrokeDashArray and StrokeDashOffset are not defined by Android, but
+	// inlined by some tools instead of a path effect.
+	StrokeDashArray  string  `xml:"strokeDashArray,attr" json:"strokeDashArray,omitempty"`
+	StrokeDashOffset float64 `xml:"strokeDashOffset,attr" json:"strokeDashOffset,omitempty"`
+	PathData         string  `xml:"pathData,attr" json:"pathData"`
+	// Drawable references a vector drawable drawn instead of the path,
+	// which Android does not support but some icon setups use.
+	Drawable string `xml:"drawable,attr" json:"drawable,omitempty"`
+	// Attrs are the attributes given as <aapt:attr> child elements, such as
+	// the gradients of the fill and stroke colors.
+	Attrs []aaptAttr `xml:"attr" json:"attrs,omitempty"`
+}
+
+// gradient returns the gradient given as <aapt:attr> for the attribute, or
+// nil. The names of the attributes have the android prefix, see newDecoder.
+func (p *vectorPath) gradient(attr string) *vectorGradient {
+	for _, a := range p.Attrs {
+		if a.Name == "android:"+attr && a.Gradient != nil {
+			return a.Gradient
+		}
+	}
 	return nil
 }
 
-func main() {
-	colorDefs := make(colorDefs)
-	colorsFile := ""
-	ios := false
-	scaleFactor := 1.0
-	showVersion := false
-	vectorFile := ""
-	pngFile := ""
-	transform := transform{}
-
-	flag.Var(&colorDefs, "color", "Defines an (A)RGB value for a color name (name=#(a)rgb|(aa)rrggbb)")
-	flag.StringVar(&colorsFile, "colors", colorsFile, "Defines an Android color resource file to be parsed for color definitions")
-	flag.Float64Var(&scaleFactor, "scale", scaleFactor, "Scales the image by the given factor")
-	flag.Float64Var(&transform.Width, "width", transform.Width, "Overrides the canvas width attribute of the vector drawable")
-	flag.Float64Var(&transform.Height, "height", transform.Height, "Overrides the canvas height attribute of the vector drawable")
-	flag.Float64Var(&transform.OffsetX, "x", transform.OffsetX, "Translates the image in x direction")
-	flag.Float64Var(&transform.OffsetY, "y", transform.OffsetY, "Translates the image in y direction")
-	flag.BoolVar(&ios, "ios", ios, "Generates three resolutions of the image (adds @2x and @3x versions)")
-	flag.BoolVar(&showVersion, "version", false, "Shows the program version")
-	flag.Usage = func() {
-		fmt.Printf("Usage: %s [options] <vector-image-input> [<png-image-output>]\n\n", filepath.Base(os.Args[0]))
-		flag.PrintDefaults()
-		fmt.Println()
-	}
-	flag.Parse()
-
-	if showVersion {
-		fmt.Println(version)
-		os.Exit(0)
-	}
-
-	if flag.NArg() == 1 {
-		vectorFile = flag.Arg(0)
-		pngFile = pathWithoutExtension(vectorFile) + ".png"
-	} else if flag.NArg() == 2 {
-		vectorFile = flag.Arg(0)
-		pngFile = flag.Arg(1)
-	} else {
-		fmt.Println("ERROR: Input vector image parameter is missing")
-		flag.Usage()
-		os.Exit(1)
-	}
-
-	if colorsFile != "" {
-		parseColorsFile(colorsFile, &colorDefs)
-	}
-
-	xmlData, err := os.ReadFile(vectorFile)
+// Convert parses the given Android vector drawable and renders it to a
+// canvas.
+func Convert(xmlData []byte, opts Options) (*canvas.Canvas, error) {
+	vec, err := parseVector(xmlData, opts)
 	if err != nil {
-		errorExit("Cannot read vector file", err)
+		return nil, err
 	}
+	return renderVectors([]*vector{vec}, opts)
+}
 
-	var vec vector
-	if err := xml.Unmarshal(xmlData, &vec); err != nil {
-		errorExit("Cannot parse vector file", err)
-	} else if vec.XMLName.Local != "vector" {
-		errorExit("Not a valid Android vector drawable", nil)
-	}
+// Path is a path of a vector drawable defined in code, see ConvertPaths.
+// The fields correspond to the attributes of the <path> element.
+type Path struct {
+	PathData    string
+	FillColor   string
+	StrokeColor string
+	StrokeWidth float64
+}
 
-	c, err := renderVector(&vec, colorDefs, transform)
-	if err != nil {
-		errorExit("Cannot render vector file", err)
+// ConvertPaths renders the paths like Convert renders a vector drawable of
+// width by height dp with a viewport of the same size. It allows trying
+// path data without writing a vector drawable.
+func ConvertPaths(width float64, height float64, paths []Path, opts Options) (*canvas.Canvas, error) {
+	vec := &vector{
+		Width:          strconv.FormatFloat(width, 'g', -1, 64) + "dp",
+		Height:         strconv.FormatFloat(height, 'g', -1, 64) + "dp",
+		ViewportWidth:  width,
+		ViewportHeight: height,
+		Alpha:          1,
 	}
+	for _, path := range paths {
+		vec.Children = append(vec.Children, vectorNode{Path: &vectorPath{
+			PathData:         path.PathData,
+			FillColor:        path.FillColor,
+			StrokeColor:      path.StrokeColor,
+			StrokeWidth:      path.StrokeWidth,
+			FillAlpha:        1,
+			StrokeAlpha:      1,
+			StrokeMiterLimit: 4,
+			TrimPathEnd:      1,
+		}})
+	}
+	if err := vec.validate(); err != nil {
+		return nil, err
+	}
+	if opts.Opacity > 1 {
+		return nil, fmt.Errorf("opacity %g must not be greater than 1", opts.Opacity)
+	}
+	return renderVectors([]*vector{vec}, opts)
+}
 
-	saveCanvas(c, pngFile, scaleFactor)
-	if ios {
-		saveCanvas(c, pathWithoutExtension(pngFile)+"@2x.png", 2*scaleFactor)
-		saveCanvas(c, pathWithoutExtension(pngFile)+"@3x.png", 3*scaleFactor)
+// ConvertLayers renders several Android vector drawables onto one canvas,
+// the first at the bottom. The canvas has the size of the first drawable and
+// the drawings of the others are scaled to it, like the background and
+// foreground layers of an adaptive icon. Errors name the index of the layer.
+func ConvertLayers(layers [][]byte, opts Options) (*canvas.Canvas, error) {
+	if len(layers) == 0 {
+		return nil, errors.New("no layers given")
 	}
+	vecs := make([]*vector, len(layers))
+	for i, xmlData := range layers {
+		vec, err := parseVector(xmlData, opts)
+		if err != nil {
+			return nil, layerError(err, i, len(layers))
+		}
+		vecs[i] = vec
+	}
+	return renderVectors(vecs, opts)
 }
 
-func renderVector(vec *vector, colorDefs colorDefs, transform transform) (*canvas.Canvas, error) {
-	originalWidth, err := parseDpNum(vec.Width, "width")
+// parseVector parses and validates a vector drawable and reports its
+// unsupported elements and attributes.
+func parseVector(xmlData []byte, opts Options) (*vector, error) {
+	vec, err := decodeVector(xmlData)
+	if err != nil {
+		return nil, err
+	}
+	if opts.Opacity > 1 {
+		return nil, fmt.Errorf("opacity %g must not be greater than 1", opts.Opacity)
+	}
+
+	warnings, err := checkSupported(xmlData)
 	if err != nil {
 		return nil, err
 	}
+	if opts.Strict && len(warnings) > 0 {
+		return nil, errors.New(strings.Join(warnings, "; "))
+	}
+	if opts.Warn != nil {
+		for _, warning := range warnings {
+			opts.Warn(warning)
+		}
+	}
+	return vec, nil
+}
 
-	originalHeight, err := parseDpNum(vec.Height, "height")
+// decodeVector decodes and validates the vector drawable of xmlData, see
+// findVector.
+func decodeVector(xmlData []byte) (*vector, error) {
+	d := newDecoder(xmlData)
+	start, err := findVector(d)
 	if err != nil {
 		return nil, err
 	}
+	var vec vector
+	if err := d.DecodeElement(&vec, &start); err != nil {
+		return nil, err
+	}
+	if err := vec.validate(); err != nil {
+		return nil, err
+	}
+	return &vec, nil
+}
+
+// Stats contains statistics about the rendering of a vector drawable.
+type Stats struct {
+	// PathsDrawn and PathsSkipped count the drawn paths and the paths that
+	// are empty or invisible.
+	PathsDrawn   int
+	PathsSkipped int
+	// ColorsResolved counts the parsed colors, ColorsUnresolved the color
+	// references replaced by the default color.
+	ColorsResolved   int
+	ColorsUnresolved int
+	// ColorsClamped counts the path colors with a channel outside the range
+	// 0 to 255 after tinting, which was clamped.
+	ColorsClamped int
+	// Width and Height are the size of the canvas in mm.
+	Width  float64
+	Height float64
+}
+
+// Add adds the counts of s2 to s. The canvas size is not added.
+func (s *Stats) Add(s2 Stats) {
+	s.PathsDrawn += s2.PathsDrawn
+	s.PathsSkipped += s2.PathsSkipped
+	s.ColorsResolved += s2.ColorsResolved
+	s.ColorsUnresolved += s2.ColorsUnresolved
+	s.ColorsClamped += s2.ColorsClamped
+}
+
+// Output describes an image file written by Save.
+type Output struct {
+	File   string
+	Width  int
+	Height int
+	Scale  float64
+}
 
-	width := originalWidth
-	if transform.Width > 0 {
-		width = transform.Width
+// Save writes the canvas to the image file p, scaled by opts.Scale. If
+// opts.IOS is set, @2x and @3x versions are written next to it, or the
+// versions of opts.IOSScales. If opts.Scales is set, a version for each of
+// its factors is written instead of these. If opts.Android is set, a version
+// for each density is written to the drawable-<density> folders next to it,
+// instead of the image itself unless one of the other options is set.
+// The written files are returned in that order. opts.OutputTemplate renames
+// all of them. ICO and ICNS files contain their own set of sizes, so no
+// versions are written for them. Missing directories are created.
+func Save(c *canvas.Canvas, p string, opts Options) ([]Output, error) {
+	return SaveFS(osFS{}, c, p, opts)
+}
+
+// SaveFS writes the images like Save, but to the file system fsys instead of
+// the file system of the operating system.
+func SaveFS(fsys WriteFS, c *canvas.Canvas, p string, opts Options) ([]Output, error) {
+	scaleFactor := opts.scaleFactor(c)
+	format := opts.Format
+	if format == "" {
+		format = FormatFromPath(p)
 	}
 
-	height := originalHeight
-	if transform.Height > 0 {
-		height = transform.Height
+	templateFile := func(template string, scale float64, density string) string {
+		if opts.OutputTemplate != "" {
+			template = opts.OutputTemplate
+		}
+		return outputFile(p, template, scale, density)
+	}
+	file := p
+	if opts.OutputTemplate != "" {
+		file = templateFile(defaultTemplate, 1, "")
 	}
 
-	c := canvas.New(width, height)
-	ctx := canvas.NewContext(c)
-	ctx.SetCoordSystem(canvas.CartesianIV)
-	ctx.SetView(canvas.Identity.Scale(originalWidth/vec.ViewportWidth, originalHeight/vec.ViewportHeight))
+	outputs := []Output{{File: file, Scale: scaleFactor}}
+	if format == FormatICO || format == FormatICNS {
+		if err := saveCanvas(fsys, c, file, format, scaleFactor, &opts); err != nil {
+			return nil, err
+		}
+		for _, size := range iconSizes(format, &opts) {
+			outputs[0].Width = max(outputs[0].Width, size)
+		}
+		outputs[0].Height = outputs[0].Width
+		outputs[0].Scale = float64(outputs[0].Width) / math.Max(c.W, c.H)
+		return outputs, nil
+	}
 
-	for _, pathElem := range vec.Paths {
-		path := canvas.MustParseSVGPath(pathElem.PathData)
-		ctx.SetFillColor(canvas.Transparent)
-		ctx.SetStrokeColor(canvas.Transparent)
-		ctx.SetStrokeWidth(pathElem.StrokeWidth)
-		if pathElem.FillColor != "" {
-			c, err := parseColor(pathElem.FillColor, colorDefs)
-			if err != nil {
-				return nil, err
-			}
-			ctx.SetFillColor(c)
+	if len(opts.Scales) > 0 {
+		outputs = nil
+		for _, scale := range opts.Scales {
+			outputs = append(outputs, Output{File: templateFile(scaleTemplate, scale, ""), Scale: scale * scaleFactor})
 		}
-		if pathElem.StrokeColor != "" {
-			c, err := parseColor(pathElem.StrokeColor, colorDefs)
-			if err != nil {
-				return nil, err
+	} else if opts.IOS {
+		outputs = nil
+		for _, scale := range opts.iosScales() {
+			output := Output{File: file, Scale: scale / opts.iosBase() * scaleFactor}
+			if scale != 1 {
+				output.File = templateFile(scaleTemplate, scale, "")
 			}
-			ctx.SetStrokeColor(c)
+			outputs = append(outputs, output)
+		}
+	}
+	if opts.Android {
+		// The densities replace the image itself, but not the iOS versions
+		// or those of Scales.
+		if !opts.IOS && len(opts.Scales) == 0 {
+			outputs = nil
+		}
+		for _, density := range androidDensities {
+			outputs = append(outputs, Output{File: templateFile(androidTemplate, density.Scale, density.Name), Scale: density.Scale * scaleFactor})
+		}
+	}
+	files := make(map[string]bool)
+	for _, output := range outputs {
+		if files[output.File] {
+			return nil, fmt.Errorf("several images would be saved to the same file \"%s\"", output.File)
 		}
-		ctx.DrawPath(transform.OffsetX, transform.OffsetY, path)
+		files[output.File] = true
 	}
 
-	return c, nil
+	// All sizes are checked before any image is written.
+	for i := range outputs {
+		if err := outputs[i].setSize(c, format, &opts); err != nil {
+			return nil, err
+		}
+	}
+
+	for i := range outputs {
+		output := &outputs[i]
+		if err := saveCanvas(fsys, c, output.File, format, output.Scale, &opts); err != nil {
+			return nil, err
+		}
+	}
+	return outputs, nil
 }
 
-func saveCanvas(c *canvas.Canvas, p string, scaleFactor float64) {
-	err := renderers.Write(p, c, canvas.DPMM(scaleFactor))
-	if err != nil {
-		errorExit(fmt.Sprintf("Cannot save PNG data to \"%s\"", p), err)
+func (opts *Options) iosScales() []float64 {
+	if len(opts.IOSScales) > 0 {
+		return opts.IOSScales
 	}
+	return []float64{1, 2, 3}
 }
 
-func parseColorsFile(colorsFile string, colorDefs *colorDefs) {
-	colorsData, err := os.ReadFile(colorsFile)
-	if err != nil {
-		errorExit("Cannot read colors file", err)
+func (opts *Options) iosBase() float64 {
+	if opts.IOSBase > 0 {
+		return opts.IOSBase
 	}
-	var colorsArray colorDefsArray
-	err = xml.Unmarshal(colorsData, &colorsArray)
-	if err != nil {
-		errorExit("Cannot parse colors file", err)
-	}
-
-	colors := colorsArray.Colors
-	var remainingColors []colorDef
-	for {
-		for _, colorDef := range colors {
-			color, err := parseColor(colorDef.Color, *colorDefs)
-			if err == nil {
-				(*colorDefs)["@color/"+colorDef.Name] = color
-			} else {
-				remainingColors = append(remainingColors, colorDef)
-			}
-		}
-		if len(remainingColors) == 0 || len(colors) == len(remainingColors) {
-			break
+	return 1
+}
+
+// Write writes the canvas as a single image in the given format to w,
+// scaled like the image file written by Save. No iOS or Android versions are
+// written. If format is empty, a PNG image is written. The returned output
+// has no file name.
+func Write(w io.Writer, c *canvas.Canvas, format Format, opts Options) (Output, error) {
+	if format == "" {
+		format = FormatPNG
+	}
+	scaleFactor := opts.scaleFactor(c)
+	output := Output{Scale: scaleFactor}
+	if format == FormatICO || format == FormatICNS {
+		output.Width = slices.Max(iconSizes(format, &opts))
+		output.Height = output.Width
+		output.Scale = float64(output.Width) / math.Max(c.W, c.H)
+	} else if err := output.setSize(c, format, &opts); err != nil {
+		return Output{}, err
+	}
+	if err := writeImage(w, c, format, scaleFactor, &opts); err != nil {
+		return Output{}, err
+	}
+	return output, nil
+}
+
+// setSize sets the pixel size of the output at its scale. Raster images
+// larger than the pixel limit of opts are an error.
+func (output *Output) setSize(c *canvas.Canvas, format Format, opts *Options) error {
+	width, height := c.W*output.Scale, c.H*output.Scale
+	if format != FormatSVG && opts.MaxPixels >= 0 && width*height > float64(opts.maxPixels()) {
+		name := "image"
+		if output.File != "" {
+			name = fmt.Sprintf("image \"%s\"", output.File)
 		}
-		colors = remainingColors
+		return fmt.Errorf("%s of %.0fx%.0f pixels exceeds the limit of %d pixels", name, width, height, opts.maxPixels())
 	}
+	output.Width = int(width + 0.5)
+	output.Height = int(height + 0.5)
+	return nil
+}
+
+// outputFile returns the file that the template names for the image file p
+// at the given scale relative to it and the Android density, if any. The
+// file is placed in the directory of p.
+func outputFile(p string, template string, scale float64, density string) string {
+	name := filepath.Base(pathWithoutExtension(p))
+	file := strings.NewReplacer(
+		"{name}", name,
+		"{ext}", filepath.Ext(p),
+		"{scale}", strconv.FormatFloat(scale, 'g', -1, 64),
+		"{density}", density,
+	).Replace(template)
+	return filepath.Join(filepath.Dir(p), filepath.FromSlash(file))
 }
 
-func parseColor(c string, colorDefs colorDefs) (color.Color, error) {
-	if color, ok := colorDefs[c]; ok {
-		return color, nil
+// color parses c and falls back to the default color if c is an unresolved
+// reference. The result is counted in stats.
+func (opts *Options) color(c string, stats *Stats) (color.Color, error) {
+	c, err := resolveString(c, opts.Strings)
+	if err != nil {
+		return nil, err
 	}
+	col, err := ParseColor(c, opts.Colors)
+	if errors.Is(err, ErrUnresolvedColor) && opts.DefaultColor != nil {
+		stats.ColorsUnresolved++
+		return opts.DefaultColor, nil
+	} else if err == nil {
+		stats.ColorsResolved++
+	}
+	return col, err
+}
 
-	match := colorPattern.FindSubmatch([]byte(c))
-	if match == nil {
-		return nil, fmt.Errorf("invalid color \"%s\"", c)
-	}
-
-	spec := match[1]
-	if len(spec) == 3 {
-		r := hexToValue(spec[0])
-		g := hexToValue(spec[1])
-		b := hexToValue(spec[2])
-		return color.NRGBA{r<<4 | r, g<<4 | g, b<<4 | b, 255}, nil
-	} else if len(spec) == 4 {
-		a := hexToValue(spec[0])
-		r := hexToValue(spec[1])
-		g := hexToValue(spec[2])
-		b := hexToValue(spec[3])
-		return color.NRGBA{r<<4 | r, g<<4 | g, b<<4 | b, a<<4 | a}, nil
-	} else if len(spec) == 6 {
-		r1 := hexToValue(spec[0])
-		r2 := hexToValue(spec[1])
-		g1 := hexToValue(spec[2])
-		g2 := hexToValue(spec[3])
-		b1 := hexToValue(spec[4])
-		b2 := hexToValue(spec[5])
-		return color.NRGBA{r1<<4 | r2, g1<<4 | g2, b1<<4 | b2, 255}, nil
-	} else if len(spec) == 8 {
-		a1 := hexToValue(spec[0])
-		a2 := hexToValue(spec[1])
-		r1 := hexToValue(spec[2])
-		r2 := hexToValue(spec[3])
-		g1 := hexToValue(spec[4])
-		g2 := hexToValue(spec[5])
-		b1 := hexToValue(spec[6])
-		b2 := hexToValue(spec[7])
-		return color.NRGBA{r1<<4 | r2, g1<<4 | g2, b1<<4 | b2, a1<<4 | a2}, nil
-	} else {
-		return nil, fmt.Errorf("invalid color \"%s\"", c)
-	}
-}
-
-func parseDpNum(n string, name string) (float64, error) {
-	match := dpNumPattern.FindStringSubmatch(n)
+// opacity returns the factor for the alpha of all paths.
+func (opts *Options) opacity() float64 {
+	if opts.Opacity <= 0 {
+		return 1
+	}
+	return opts.Opacity
+}
+
+// tint returns the tint color with the alpha of c multiplied in or c itself
+// if no tint is set.
+func (opts *Options) tint(c color.Color) color.Color {
+	if opts.Tint == nil {
+		return c
+	}
+	_, _, _, a := c.RGBA()
+	tint := color.NRGBAModel.Convert(opts.Tint).(color.NRGBA)
+	tint.A = uint8(uint32(tint.A) * a / 0xffff)
+	return tint
+}
+
+// maxPixels returns the pixel limit of raster images.
+func (opts *Options) maxPixels() int {
+	if opts.MaxPixels == 0 {
+		return DefaultMaxPixels
+	}
+	return opts.MaxPixels
+}
+
+// scaleFactor returns the factor by which the canvas is scaled when saved.
+// One canvas unit (dp) corresponds to one pixel at a factor of 1, so a given
+// pixel width or height is reached by dividing it by the canvas size.
+func (opts *Options) scaleFactor(c *canvas.Canvas) float64 {
+	if opts.PixelWidth > 0 {
+		return float64(opts.PixelWidth) / c.W
+	} else if opts.PixelHeight > 0 {
+		return float64(opts.PixelHeight) / c.H
+	} else if opts.Scale > 0 {
+		return opts.Scale
+	}
+	return 1
+}
+
+// parseDimension parses a dimension such as "24dp" and returns it in dp. The
+// units dp, dip and sp are equivalent, px refers to the given density (mdpi
+// if not greater than zero) and mm, in and pt are physical sizes. A number
+// without unit is taken as dp.
+func parseDimension(n string, name string, density float64) (float64, error) {
+	match := dimensionPattern.FindStringSubmatch(strings.TrimSpace(n))
 	if match == nil {
 		return 0, fmt.Errorf("invalid %s \"%s\"", name, n)
 	}
 
-	width, err := strconv.ParseFloat(match[1], 32)
+	value, err := strconv.ParseFloat(match[1], 64)
 	if err != nil {
 		return 0, err
 	}
-	return width, nil
+	if density <= 0 {
+		density = mdpi
+	}
+	switch match[2] {
+	case "", "dp", "dip", "sp":
+		return value, nil
+	case "px":
+		return value * mdpi / density, nil
+	case "mm":
+		return value * mdpi / 25.4, nil
+	case "in":
+		return value * mdpi, nil
+	case "pt":
+		return value * mdpi / 72, nil
+	}
+	return 0, fmt.Errorf("unsupported unit \"%s\" in %s \"%s\"", match[2], name, n)
 }
 
-func hexToValue(n byte) uint8 {
-	if n >= '0' && n <= '9' {
-		return n - '0'
-	} else if n >= 'a' && n <= 'f' {
-		return n - 'a' + 10
-	} else if n >= 'A' && n <= 'F' {
-		return n - 'A' + 10
+// snippet shortens s to at most n characters for error messages.
+func snippet(s string, n int) string {
+	if r := []rune(s); len(r) > n {
+		return string(r[:n]) + "..."
 	}
-	return 0
+	return s
 }
 
 func pathWithoutExtension(p string) string {
 	return strings.TrimSuffix(p, filepath.Ext(p))
 }
-
-func errorExit(msg string, err error) {
-	fmt.Print("ERROR: ")
-	fmt.Print(msg)
-	if err != nil {
-		fmt.Print(" (")
-		fmt.Print(err)
-		fmt.Print(")")
-	}
-	fmt.Println()
-	os.Exit(1)
-}
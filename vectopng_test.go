@@ -0,0 +1,400 @@
+package vectopng
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"strings"
+	"testing"
+
+	"github.com/tdewolff/canvas"
+)
+
+// testVector wraps the elements in a vector drawable of 100 by 100 dp with
+// a viewport of the same size.
+func testVector(elements string) string {
+	return fmt.Sprintf(`<vector xmlns:android="http://schemas.android.com/apk/res/android"
+    android:width="100dp" android:height="100dp"
+    android:viewportWidth="100" android:viewportHeight="100">%s</vector>`, elements)
+}
+
+// render converts the vector drawable and rasterizes it like Save.
+func render(t *testing.T, xmlData string, opts Options) image.Image {
+	t.Helper()
+	c, err := Convert([]byte(xmlData), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return Rasterize(c, opts)
+}
+
+// alphaAt returns the alpha of the pixel at x, y between 0 and 255.
+func alphaAt(img image.Image, x int, y int) uint8 {
+	_, _, _, a := img.At(x, y).RGBA()
+	return uint8(a >> 8)
+}
+
+// viewRecorder is a renderer that records the matrices of the paths.
+type viewRecorder struct {
+	width, height float64
+	views         []canvas.Matrix
+}
+
+func (r *viewRecorder) Size() (float64, float64) { return r.width, r.height }
+
+func (r *viewRecorder) RenderPath(path *canvas.Path, style canvas.Style, m canvas.Matrix) {
+	r.views = append(r.views, m)
+}
+
+func (r *viewRecorder) RenderText(text *canvas.Text, m canvas.Matrix) {}
+
+func (r *viewRecorder) RenderImage(img image.Image, m canvas.Matrix) {}
+
+func TestViewMatrix(t *testing.T) {
+	// The values are variables, so that the expected matrices are computed
+	// in float64 like the view and not as exact constants.
+	width, height, viewportWidth, viewportHeight := 100.3, 33.1, 17.7, 7.9
+	large, largeViewport := 1234567.891, 0.1234567
+	tests := []struct {
+		name   string
+		vector string
+		want   canvas.Matrix
+	}{
+		{
+			name:   "fractional viewport",
+			vector: `android:width="100.3dp" android:height="33.1dp" android:viewportWidth="17.7" android:viewportHeight="7.9"`,
+			want:   canvas.Matrix{{width / viewportWidth, 0, 0}, {0, -height / viewportHeight, height}},
+		},
+		{
+			// float32 precision would lose the last digits of both.
+			name:   "large size and small viewport",
+			vector: `android:width="1234567.891dp" android:height="1234567.891dp" android:viewportWidth="0.1234567" android:viewportHeight="0.1234567"`,
+			want:   canvas.Matrix{{large / largeViewport, 0, 0}, {0, -large / largeViewport, large}},
+		},
+		{
+			// A missing viewport dimension maps one unit to one dp.
+			name:   "missing viewport width",
+			vector: `android:width="50dp" android:height="20dp" android:viewportHeight="10"`,
+			want:   canvas.Matrix{{1, 0, 0}, {0, -2, 20}},
+		},
+		{
+			name:   "missing viewport height",
+			vector: `android:width="50dp" android:height="20dp" android:viewportWidth="25"`,
+			want:   canvas.Matrix{{2, 0, 0}, {0, -1, 20}},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			xmlData := `<vector xmlns:android="http://schemas.android.com/apk/res/android" ` + test.vector + `>
+  <path android:fillColor="#000000" android:pathData="M0,0h1v1h-1z"/>
+</vector>`
+			c, err := Convert([]byte(xmlData), Options{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			r := &viewRecorder{width: c.W, height: c.H}
+			c.RenderTo(r)
+			if len(r.views) != 1 || r.views[0] != test.want {
+				t.Errorf("got views %v, want %v", r.views, test.want)
+			}
+		})
+	}
+}
+
+func TestParseDimension(t *testing.T) {
+	tests := []struct {
+		value   string
+		density float64
+		want    float64
+	}{
+		{"24", 0, 24},
+		{"23.5dp", 0, 23.5},
+		{".5dp", 0, 0.5},
+		{"12.dp", 0, 12},
+		{"1e1dp", 0, 10},
+		{"2.5E-1dp", 0, 0.25},
+		{"1e+2", 0, 100},
+		{"24dip", 0, 24},
+		{"24sp", 0, 24},
+		{"24px", 0, 24},
+		{"48px", 320, 24},
+		{"25.4mm", 0, 160},
+		{"0.5in", 0, 80},
+		{"72pt", 0, 160},
+	}
+	for _, test := range tests {
+		t.Run(test.value, func(t *testing.T) {
+			got, err := parseDimension(test.value, "width", test.density)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != test.want {
+				t.Errorf("got %g, want %g", got, test.want)
+			}
+		})
+	}
+}
+
+func TestParseDimensionErrors(t *testing.T) {
+	tests := []struct {
+		value string
+		want  string
+	}{
+		{"24cm", `unsupported unit "cm" in width "24cm"`},
+		{"24%", `unsupported unit "%" in width "24%"`},
+		{"dp", `invalid width "dp"`},
+		{"1e", `unsupported unit "e" in width "1e"`},
+		{"-24dp", `invalid width "-24dp"`},
+	}
+	for _, test := range tests {
+		t.Run(test.value, func(t *testing.T) {
+			_, err := parseDimension(test.value, "width", 0)
+			if err == nil || !strings.Contains(err.Error(), test.want) {
+				t.Errorf("got error %v, want %q", err, test.want)
+			}
+		})
+	}
+}
+
+func TestNumberForms(t *testing.T) {
+	// The square and the line use fractional and exponent numbers in the
+	// path data and the stroke width.
+	img := render(t, testVector(`
+  <path android:fillColor="#000000" android:pathData="M.1e2,1e1H.5E2V4.e1h-4e1z"/>
+  <path android:strokeColor="#000000" android:strokeWidth="1.5e1" android:pathData="M10,70h8e1"/>`), Options{})
+	tests := []struct {
+		name  string
+		x, y  int
+		alpha uint8
+	}{
+		{"inside the square", 30, 25, 255},
+		{"right of the square", 55, 25, 0},
+		{"inside the stroke", 50, 63, 255},
+		{"above the stroke", 50, 61, 0},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := alphaAt(img, test.x, test.y); got != test.alpha {
+				t.Errorf("got alpha %d at %d,%d, want %d", got, test.x, test.y, test.alpha)
+			}
+		})
+	}
+}
+
+func TestStrokeOnly(t *testing.T) {
+	tests := []struct {
+		name    string
+		attrs   string
+		stroked bool
+		warning string
+	}{
+		{
+			name:    "stroke width",
+			attrs:   `android:strokeColor="#000000" android:strokeWidth="10"`,
+			stroked: true,
+		},
+		{
+			name:    "no stroke width",
+			attrs:   `android:strokeColor="#000000"`,
+			warning: "path 0 has a strokeColor but no strokeWidth",
+		},
+		{
+			name:    "zero stroke width",
+			attrs:   `android:strokeColor="#000000" android:strokeWidth="0"`,
+			warning: "path 0 has a strokeColor but no strokeWidth",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var warnings []string
+			opts := Options{Warn: func(msg string) { warnings = append(warnings, msg) }}
+			img := render(t, testVector(`<path `+test.attrs+` android:pathData="M20,20h60v60h-60z"/>`), opts)
+			// The outline is painted, the inside is not filled.
+			if got, want := alphaAt(img, 20, 50) == 255, test.stroked; got != want {
+				t.Errorf("stroked %v, want %v", got, want)
+			}
+			if got := alphaAt(img, 50, 50); got != 0 {
+				t.Errorf("got alpha %d inside, want 0", got)
+			}
+			if test.warning == "" {
+				if len(warnings) > 0 {
+					t.Errorf("unexpected warnings %q", warnings)
+				}
+			} else if len(warnings) != 1 || !strings.Contains(warnings[0], test.warning) {
+				t.Errorf("got warnings %q, want %q", warnings, test.warning)
+			}
+		})
+	}
+}
+
+func TestNamespacePrefixes(t *testing.T) {
+	tests := []struct {
+		name   string
+		vector string
+	}{
+		{
+			name: "android prefix",
+			vector: `<vector xmlns:android="http://schemas.android.com/apk/res/android"
+    android:width="10dp" android:height="10dp" android:viewportWidth="10" android:viewportHeight="10">
+  <path android:fillColor="#ff0000" android:pathData="M0,0h10v10h-10z"/>
+</vector>`,
+		},
+		{
+			name: "other prefix",
+			vector: `<vector xmlns:a="http://schemas.android.com/apk/res/android"
+    a:width="10dp" a:height="10dp" a:viewportWidth="10" a:viewportHeight="10">
+  <path a:fillColor="#ff0000" a:pathData="M0,0h10v10h-10z"/>
+</vector>`,
+		},
+		{
+			name: "default namespace",
+			vector: `<vector xmlns="http://schemas.android.com/apk/res/android"
+    width="10dp" height="10dp" viewportWidth="10" viewportHeight="10">
+  <path fillColor="#ff0000" pathData="M0,0h10v10h-10z"/>
+</vector>`,
+		},
+		{
+			name: "gradient under other prefixes",
+			vector: `<vector xmlns:a="http://schemas.android.com/apk/res/android" xmlns:x="http://schemas.android.com/aapt"
+    a:width="10dp" a:height="10dp" a:viewportWidth="10" a:viewportHeight="10">
+  <path a:pathData="M0,0h10v10h-10z">
+    <x:attr name="a:fillColor">
+      <gradient a:endX="10" a:startColor="#ff0000" a:endColor="#ff0000"/>
+    </x:attr>
+  </path>
+</vector>`,
+		},
+		{
+			name: "gradient with a nested declaration",
+			vector: `<vector xmlns:android="http://schemas.android.com/apk/res/android" xmlns:aapt="http://schemas.android.com/aapt"
+    android:width="10dp" android:height="10dp" android:viewportWidth="10" android:viewportHeight="10">
+  <path android:pathData="M0,0h10v10h-10z">
+    <aapt:attr xmlns:b="http://schemas.android.com/apk/res/android" name="b:fillColor">
+      <gradient b:endX="10" b:startColor="#ff0000" b:endColor="#ff0000"/>
+    </aapt:attr>
+  </path>
+</vector>`,
+		},
+		{
+			name: "tools attributes",
+			vector: `<vector xmlns:android="http://schemas.android.com/apk/res/android"
+    xmlns:tools="http://schemas.android.com/tools"
+    android:width="10dp" android:height="10dp" android:viewportWidth="10" android:viewportHeight="10">
+  <path tools:fillColor="#0000ff" android:fillColor="#ff0000" android:pathData="M0,0h10v10h-10z" tools:pathData="M0,0h1v1h-1z"/>
+</vector>`,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var warnings []string
+			opts := Options{Warn: func(msg string) { warnings = append(warnings, msg) }}
+			img := render(t, test.vector, opts).(*image.RGBA)
+			if got, want := img.Bounds().Size(), (image.Point{10, 10}); got != want {
+				t.Errorf("got size %v, want %v", got, want)
+			}
+			if got, want := img.RGBAAt(5, 5), (color.RGBA{255, 0, 0, 255}); got != want {
+				t.Errorf("got %v, want %v", got, want)
+			}
+			if len(warnings) > 0 {
+				t.Errorf("unexpected warnings %q", warnings)
+			}
+		})
+	}
+}
+
+func TestDashedStroke(t *testing.T) {
+	tests := []struct {
+		name  string
+		attrs string
+		// painted lists whether the line is painted at x = 15, 25, 35 and 45.
+		painted [4]bool
+	}{
+		{"solid", ``, [4]bool{true, true, true, true}},
+		{"dashes", `android:strokeDashArray="10,10"`, [4]bool{true, false, true, false}},
+		{"spaces", `android:strokeDashArray="10 10"`, [4]bool{true, false, true, false}},
+		{"offset", `android:strokeDashArray="10,10" android:strokeDashOffset="10"`, [4]bool{false, true, false, true}},
+		{"uneven", `android:strokeDashArray="20,10"`, [4]bool{true, true, false, true}},
+		{"single value", `android:strokeDashArray="10"`, [4]bool{true, true, true, true}},
+		{"empty", `android:strokeDashArray=""`, [4]bool{true, true, true, true}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			img := render(t, testVector(`<path android:strokeColor="#000000" android:strokeWidth="4" `+test.attrs+` android:pathData="M10,50h80"/>`), Options{})
+			for i, want := range test.painted {
+				x := 15 + 10*i
+				if got := alphaAt(img, x, 50) == 255; got != want {
+					t.Errorf("painted %v at x = %d, want %v", got, x, want)
+				}
+			}
+		})
+	}
+}
+
+func TestDashArrayErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+		want  string
+	}{
+		{"negative", "10,-2", `invalid strokeDashArray "10,-2" of path 0: negative length -2`},
+		{"not a number", "10,x", `invalid strokeDashArray "10,x" of path 0`},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := Convert([]byte(testVector(`<path android:strokeColor="#000000" android:strokeWidth="4" android:strokeDashArray="`+test.value+`" android:pathData="M10,50h80"/>`)), Options{})
+			if err == nil || !strings.Contains(err.Error(), test.want) {
+				t.Errorf("got error %v, want %q", err, test.want)
+			}
+		})
+	}
+}
+
+func TestLinearBlend(t *testing.T) {
+	// The rectangles overlap between x = 40 and 60.
+	xmlData := testVector(`
+  <path android:fillColor="#ff0000" android:fillAlpha="0.5" android:pathData="M0,0h60v100h-60z"/>
+  <path android:fillColor="#0000ff" android:fillAlpha="0.5" android:pathData="M40,0h60v100h-60z"/>`)
+	tests := []struct {
+		name    string
+		opts    Options
+		red     color.RGBA
+		overlap color.RGBA
+	}{
+		{
+			name:    "sRGB on white",
+			opts:    Options{Background: color.White},
+			red:     color.RGBA{255, 127, 127, 255},
+			overlap: color.RGBA{127, 63, 191, 255},
+		},
+		{
+			// Half of a color blended in linear RGB is lighter in sRGB.
+			name:    "linear on white",
+			opts:    Options{Background: color.White, LinearBlend: true},
+			red:     color.RGBA{255, 187, 187, 255},
+			overlap: color.RGBA{187, 136, 224, 255},
+		},
+		{
+			name:    "sRGB on transparent",
+			opts:    Options{},
+			red:     color.RGBA{128, 0, 0, 128},
+			overlap: color.RGBA{63, 0, 128, 192},
+		},
+		{
+			name:    "linear on transparent",
+			opts:    Options{LinearBlend: true},
+			red:     color.RGBA{128, 0, 0, 128},
+			overlap: color.RGBA{117, 0, 161, 192},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			img := render(t, xmlData, test.opts).(*image.RGBA)
+			if got := img.RGBAAt(20, 50); got != test.red {
+				t.Errorf("got %v without overlap, want %v", got, test.red)
+			}
+			if got := img.RGBAAt(50, 50); got != test.overlap {
+				t.Errorf("got %v in the overlap, want %v", got, test.overlap)
+			}
+		})
+	}
+}